@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// aqlItem is an item returned by the AQL search API.
+type aqlItem struct {
+	Repo   string `json:"repo"`
+	Path   string `json:"path"`
+	Name   string `json:"name"`
+	Sha256 string `json:"sha256"`
+}
+
+// searchAQL runs an items.find query through the AQL search API and returns
+// the items found as artifacts, without going through the jfrog CLI.
+func searchAQL(ctx context.Context, args Args, sanitizedURL string, find map[string]interface{}) ([]Artifact, error) {
+	criteria, err := json.Marshal(find)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding AQL query: %w", err)
+	}
+	query := "items.find(" + string(criteria) + `).include("repo","path","name","sha256")`
+
+	req, err := newRequest(ctx, http.MethodPost, sanitizedURL+"api/search/aql", strings.NewReader(query), args)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+	body, err := doRequest(req, args)
+	if err != nil {
+		return nil, fmt.Errorf("error running AQL search: %w", err)
+	}
+
+	var response struct {
+		Results []aqlItem `json:"results"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing AQL search results: %w", err)
+	}
+	artifacts := make([]Artifact, 0, len(response.Results))
+	for _, item := range response.Results {
+		artifacts = append(artifacts, Artifact{
+			Path:   item.Repo + "/" + item.Path + "/" + item.Name,
+			Sha256: item.Sha256,
+		})
+	}
+	return artifacts, nil
+}
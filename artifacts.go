@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// searchResult is an item returned by jfrog rt search.
+type searchResult struct {
+	Path   string `json:"path"`
+	Type   string `json:"type"`
+	Sha1   string `json:"sha1"`
+	Sha256 string `json:"sha256"`
+	Md5    string `json:"md5"`
+}
+
+// uploadArtifacts uploads the files of a file spec as part of the build, so the
+// jfrog CLI records them as artifacts when the build is published.
+func uploadArtifacts(args Args, sanitizedURL string) error {
+	logrus.Infof("Uploading the artifacts of %s", args.UploadSpec)
+	cmdArgs := []string{"jfrog", "rt", "upload", "--spec=" + args.UploadSpec, "--build-name=" + args.BuildName, "--build-number=" + args.BuildNumber, "--module=" + artifactsModuleID(args), "--url=" + sanitizedURL}
+	cmdArgs = appendProjectFlag(cmdArgs, args)
+	cmdArgs, err := setAuthParams(cmdArgs, args)
+	if err != nil {
+		logrus.Errorf("error setting auth parameters: %v", err)
+	}
+	if err := runCommand(cmdArgs); err != nil {
+		return fmt.Errorf("error executing jfrog rt upload command: %w", err)
+	}
+	return nil
+}
+
+// searchArtifacts returns the build-info artifacts for the files already in
+// Artifactory that match the comma separated search patterns.
+func searchArtifacts(args Args, sanitizedURL string) ([]map[string]interface{}, error) {
+	var artifacts []map[string]interface{}
+	for _, pattern := range strings.Split(args.Artifacts, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		cmdArgs := []string{"jfrog", "rt", "search", pattern, "--url=" + sanitizedURL}
+		cmdArgs, err := setAuthParams(cmdArgs, args)
+		if err != nil {
+			logrus.Errorf("error setting auth parameters: %v", err)
+		}
+		output, err := runCommandAndCaptureStdout(cmdArgs)
+		if err != nil {
+			return nil, fmt.Errorf("error executing jfrog rt search command: %w", err)
+		}
+		var results []searchResult
+		if err := json.Unmarshal(output, &results); err != nil {
+			return nil, fmt.Errorf("error parsing jfrog rt search output: %w", err)
+		}
+		if len(results) == 0 {
+			if err := warnOrFail(args, "No artifacts found for %s", pattern); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, result := range results {
+			repo, itemPath, _ := strings.Cut(result.Path, "/")
+			artifacts = append(artifacts, map[string]interface{}{
+				"type":                   strings.TrimPrefix(path.Ext(itemPath), "."),
+				"name":                   path.Base(itemPath),
+				"path":                   itemPath,
+				"originalDeploymentRepo": repo,
+				"sha1":                   result.Sha1,
+				"sha256":                 result.Sha256,
+				"md5":                    result.Md5,
+			})
+		}
+	}
+	return artifacts, nil
+}
+
+// artifactsModuleID returns the ID of the module the generic artifacts are recorded in.
+func artifactsModuleID(args Args) string {
+	return firstNonEmpty(args.ArtifactsModule, args.BuildName+"-artifacts")
+}
+
+// addModuleArtifacts returns an edit that adds artifacts to the module with the
+// given ID, creating a generic module if the build info has none.
+func addModuleArtifacts(moduleID string, artifacts []map[string]interface{}) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		modules, _ := buildInfo["modules"].([]interface{})
+		var module map[string]interface{}
+		for _, m := range modules {
+			if candidate, ok := m.(map[string]interface{}); ok && candidate["id"] == moduleID {
+				module = candidate
+				break
+			}
+		}
+		if module == nil {
+			module = map[string]interface{}{"type": "generic", "id": moduleID}
+			buildInfo["modules"] = append(modules, module)
+		}
+
+		existing, _ := module["artifacts"].([]interface{})
+		for _, artifact := range artifacts {
+			existing = append(existing, artifact)
+		}
+		module["artifacts"] = existing
+		return nil
+	}
+}
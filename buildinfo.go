@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// buildInfoTimeFormat is the timestamp format of the build info "started" field.
+const buildInfoTimeFormat = "2006-01-02T15:04:05.000-0700"
+
+// defaultBuildInfoRepo is the repository builds are published to outside of a project.
+const defaultBuildInfoRepo = "artifactory-build-info"
+
+// buildInfoEdit modifies the build info assembled by the jfrog CLI before it is published.
+type buildInfoEdit func(buildInfo map[string]interface{}) error
+
+// publishBuildInfo publishes the collected build information to JFrog. Without
+// edits the jfrog CLI publishes it directly. With edits the build info is
+// assembled with a dry-run publish, edited, deployed through the REST API and
+// the local build partials are cleaned up as the CLI would after publishing.
+func publishBuildInfo(ctx context.Context, args Args, sanitizedURL string, edits []buildInfoEdit) error {
+	logrus.Info("Publishing Build Info")
+	cmdArgs := buildPublishArgs(args, sanitizedURL)
+
+	// Execute the build publish command
+	if len(edits) == 0 {
+		if err := runCommandWithRetries(ctx, args, cmdArgs); err != nil {
+			return fmt.Errorf("error executing jfrog rt build-publish command: %w", err)
+		}
+		return nil
+	}
+
+	buildInfo, err := assembleBuildInfo(cmdArgs)
+	if err != nil {
+		return err
+	}
+
+	for _, edit := range edits {
+		if err := edit(buildInfo); err != nil {
+			return err
+		}
+	}
+
+	// Deploy the edited build info
+	body, err := encodeBuildInfo(buildInfo)
+	if err != nil {
+		return fmt.Errorf("error encoding build info: %w", err)
+	}
+	publishURL := buildAPIURL(sanitizedURL, args, nil)
+	req, err := newRequest(ctx, http.MethodPut, publishURL, bytes.NewReader(body), args)
+	if err != nil {
+		return fmt.Errorf("error deploying build info: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/vnd.org.jfrog.artifactory+json")
+	if _, err := doRequest(req, args); err != nil {
+		return fmt.Errorf("error deploying build info: %w", err)
+	}
+	logrus.Info("Build info successfully deployed")
+
+	// Remove the local build partials now that the build is published
+	cmdArgs = appendProjectFlag([]string{"jfrog", "rt", "build-clean", args.BuildName, args.BuildNumber}, args)
+	if err := runCommand(cmdArgs); err != nil {
+		return fmt.Errorf("error executing jfrog rt build-clean command: %w", err)
+	}
+	return nil
+}
+
+// buildPublishArgs returns the jfrog rt build-publish command of the build.
+func buildPublishArgs(args Args, sanitizedURL string) []string {
+	cmdArgs := []string{"jfrog", "rt", "build-publish", "--build-url=" + args.BuildURL, "--url=" + sanitizedURL, args.BuildName, args.BuildNumber}
+	cmdArgs = appendProjectFlag(cmdArgs, args)
+	cmdArgs, err := setAuthParams(cmdArgs, args)
+	if err != nil {
+		logrus.Errorf("error setting auth parameters: %v", err)
+	}
+	return cmdArgs
+}
+
+// assembleBuildInfo returns the build info the jfrog rt build-publish command
+// would publish, assembled from the build partials without sending it to
+// Artifactory.
+func assembleBuildInfo(publishArgs []string) (map[string]interface{}, error) {
+	output, err := runCommandAndCaptureStdout(append(publishArgs, "--dry-run"))
+	if err != nil {
+		return nil, fmt.Errorf("error executing jfrog rt build-publish --dry-run command: %w", err)
+	}
+	buildInfo, err := decodeBuildInfo(output)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing build info from jfrog rt build-publish --dry-run: %w", err)
+	}
+	return buildInfo, nil
+}
+
+// decodeBuildInfo parses build info JSON into a map, keeping every field,
+// including those this plugin does not know about. Numbers are kept as
+// json.Number, so they are deployed exactly as the jfrog CLI wrote them rather
+// than rounded through float64.
+func decodeBuildInfo(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var buildInfo map[string]interface{}
+	if err := decoder.Decode(&buildInfo); err != nil {
+		return nil, err
+	}
+	if buildInfo == nil {
+		return nil, errors.New("build info is empty")
+	}
+	return buildInfo, nil
+}
+
+// encodeBuildInfo encodes the build info for deployment. Strings are not HTML
+// escaped, so values come back as they were decoded.
+func encodeBuildInfo(buildInfo map[string]interface{}) ([]byte, error) {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(buildInfo); err != nil {
+		return nil, err
+	}
+	return body.Bytes(), nil
+}
+
+// addModule returns an edit that appends a module to the build info.
+func addModule(module map[string]interface{}) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		modules, _ := buildInfo["modules"].([]interface{})
+		buildInfo["modules"] = append(modules, module)
+		return nil
+	}
+}
+
+// buildAPIURL returns the URL of the build REST API, followed by the given
+// build name and number path segments and the query, with the project of the
+// build-info repository added. Every REST path to a build is built here, so
+// names with spaces, '#', '/' or non-ASCII characters are escaped alike.
+func buildAPIURL(sanitizedURL string, args Args, query url.Values, segments ...string) string {
+	requestURL := sanitizedURL + "api/build"
+	for _, segment := range segments {
+		requestURL += "/" + escapeBuildSegment(segment)
+	}
+	if project := buildInfoProject(args); project != "" {
+		if query == nil {
+			query = url.Values{}
+		}
+		query.Set("project", project)
+	}
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	return requestURL
+}
+
+// escapeBuildSegment escapes a build name or number for use as one URL path
+// segment, including any '/' it contains.
+func escapeBuildSegment(segment string) string {
+	return url.PathEscape(segment)
+}
+
+// escapeItemPath escapes a repository path, such as repo/image/tag/manifest.json,
+// for use in a URL. Each '/'-separated segment is escaped like a build name, so
+// image names and tags with spaces, '#', '%' or non-ASCII characters reach the
+// item they name.
+func escapeItemPath(itemPath string) string {
+	segments := strings.Split(itemPath, "/")
+	for i, segment := range segments {
+		segments[i] = escapeBuildSegment(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// buildInfoRepo returns the repository the build info is published to.
+func buildInfoRepo(args Args) string {
+	return firstNonEmpty(args.BuildInfoRepo, defaultBuildInfoRepo)
+}
+
+// buildInfoProject returns the key of the project whose build-info repository
+// the build is published to, or "" for the default repository. Custom
+// build-info repositories belong to a project and are named <project>-build-info.
+func buildInfoProject(args Args) string {
+	repo := buildInfoRepo(args)
+	if repo == defaultBuildInfoRepo {
+		return ""
+	}
+	return strings.TrimSuffix(repo, "-build-info")
+}
+
+// appendProjectFlag adds the project of the build-info repository to a jfrog
+// CLI build command. Every command of a build needs the same project, as the
+// CLI keeps the local build partials per project.
+func appendProjectFlag(cmdArgs []string, args Args) []string {
+	if project := buildInfoProject(args); project != "" {
+		cmdArgs = append(cmdArgs, "--project="+project)
+	}
+	return cmdArgs
+}
+
+// setBuildInfoField returns an edit that sets a top-level field of the build info.
+func setBuildInfoField(field string, value interface{}) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		buildInfo[field] = value
+		return nil
+	}
+}
+
+// setAgent returns an edit that overrides the name and/or version of the
+// "agent" or "buildAgent" section of the build info. Empty values are kept.
+func setAgent(field, name, version string) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		agent, _ := buildInfo[field].(map[string]interface{})
+		if agent == nil {
+			agent = map[string]interface{}{}
+		}
+		if name != "" {
+			agent["name"] = name
+		}
+		if version != "" {
+			agent["version"] = version
+		}
+		buildInfo[field] = agent
+		return nil
+	}
+}
+
+// parseBuildStarted parses a build start time given either as Unix seconds, as
+// DRONE_BUILD_STARTED is, or as an RFC 3339 timestamp.
+func parseBuildStarted(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	started, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid build start time %q: expected Unix seconds or RFC 3339", value)
+	}
+	return started, nil
+}
+
+// dockerModule returns the module recorded for the image with the given module
+// ID, or the first docker module when the ID is empty. It returns nil if the
+// build info has no such module.
+func dockerModule(buildInfo map[string]interface{}, moduleID string) map[string]interface{} {
+	modules, _ := buildInfo["modules"].([]interface{})
+	for _, m := range modules {
+		module, ok := m.(map[string]interface{})
+		if ok && (moduleID == "" && module["type"] == "docker" || moduleID != "" && module["id"] == moduleID) {
+			return module
+		}
+	}
+	return nil
+}
+
+// renameModule returns an edit that changes the ID of a module.
+func renameModule(moduleID, newID string) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		module := dockerModule(buildInfo, moduleID)
+		if module == nil {
+			return fmt.Errorf("build info has no docker module %s to rename", moduleID)
+		}
+		module["id"] = newID
+		return nil
+	}
+}
+
+// addModuleDependencies returns an edit that appends dependencies to the docker module of an image.
+func addModuleDependencies(moduleID string, dependencies []map[string]interface{}) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		module := dockerModule(buildInfo, moduleID)
+		if module == nil {
+			return fmt.Errorf("build info has no docker module %s to add dependencies to", moduleID)
+		}
+		existing, _ := module["dependencies"].([]interface{})
+		for _, dependency := range dependencies {
+			existing = append(existing, dependency)
+		}
+		module["dependencies"] = existing
+		return nil
+	}
+}
+
+// addModuleProperties returns an edit that adds properties to the docker module of an image.
+func addModuleProperties(moduleID string, properties map[string]string) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		module := dockerModule(buildInfo, moduleID)
+		if module == nil {
+			return fmt.Errorf("build info has no docker module %s to add properties to", moduleID)
+		}
+		existing, _ := module["properties"].(map[string]interface{})
+		if existing == nil {
+			existing = map[string]interface{}{}
+		}
+		for key, value := range properties {
+			existing[key] = value
+		}
+		module["properties"] = existing
+		return nil
+	}
+}
+
+// addBuildProperties returns an edit that adds properties to the build info.
+func addBuildProperties(properties map[string]string) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		existing, _ := buildInfo["properties"].(map[string]interface{})
+		if existing == nil {
+			existing = map[string]interface{}{}
+		}
+		for key, value := range properties {
+			existing[key] = value
+		}
+		buildInfo["properties"] = existing
+		return nil
+	}
+}
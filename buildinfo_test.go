@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBuildAPIURL(t *testing.T) {
+	const sanitizedURL = "https://example.jfrog.io/artifactory/"
+	tests := []struct {
+		name        string
+		buildName   string
+		buildNumber string
+		want        string
+	}{
+		{"plain", "app", "1", sanitizedURL + "api/build/app/1"},
+		{"space", "my app", "1", sanitizedURL + "api/build/my%20app/1"},
+		{"hash", "app#2", "1#b", sanitizedURL + "api/build/app%232/1%23b"},
+		{"slash", "team/app", "1", sanitizedURL + "api/build/team%2Fapp/1"},
+		{"percent", "app%20", "100%", sanitizedURL + "api/build/app%2520/100%25"},
+		{"non-ASCII", "café", "1", sanitizedURL + "api/build/caf%C3%A9/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildAPIURL(sanitizedURL, Args{}, nil, tt.buildName, tt.buildNumber)
+			if got != tt.want {
+				t.Errorf("buildAPIURL(%q, %q) = %q, want %q", tt.buildName, tt.buildNumber, got, tt.want)
+			}
+
+			// The server must read back the segments as given
+			parsed, err := url.Parse(got)
+			if err != nil {
+				t.Fatalf("url.Parse(%q): %v", got, err)
+			}
+			wantPath := "/artifactory/api/build/" + tt.buildName + "/" + tt.buildNumber
+			if parsed.Path != wantPath {
+				t.Errorf("decoded path = %q, want %q", parsed.Path, wantPath)
+			}
+		})
+	}
+}
+
+func TestBuildAPIURLProject(t *testing.T) {
+	got := buildAPIURL("https://example.jfrog.io/artifactory/", Args{BuildInfoRepo: "my proj-build-info"}, nil, "app", "1")
+	want := "https://example.jfrog.io/artifactory/api/build/app/1?project=my+proj"
+	if got != want {
+		t.Errorf("buildAPIURL() = %q, want %q", got, want)
+	}
+}
+
+func TestEscapeBuildSegment(t *testing.T) {
+	tests := []struct {
+		segment string
+		want    string
+	}{
+		{"app", "app"},
+		{"my app", "my%20app"},
+		{"app#1", "app%231"},
+		{"team/app", "team%2Fapp"},
+		{"100%", "100%25"},
+		{"café", "caf%C3%A9"},
+		{"ビルド", "%E3%83%93%E3%83%AB%E3%83%89"},
+	}
+	for _, tt := range tests {
+		if got := escapeBuildSegment(tt.segment); got != tt.want {
+			t.Errorf("escapeBuildSegment(%q) = %q, want %q", tt.segment, got, tt.want)
+		}
+	}
+}
+
+func TestEscapeItemPath(t *testing.T) {
+	tests := []struct {
+		itemPath string
+		want     string
+	}{
+		{"docker-local/app/1.0/manifest.json", "docker-local/app/1.0/manifest.json"},
+		{"docker-local/my app/1.0/manifest.json", "docker-local/my%20app/1.0/manifest.json"},
+		{"docker-local/app/v1#rc/manifest.json", "docker-local/app/v1%23rc/manifest.json"},
+		{"docker-local/team/app/1.0/manifest.json", "docker-local/team/app/1.0/manifest.json"},
+		{"docker-local/app/100%/manifest.json", "docker-local/app/100%25/manifest.json"},
+		{"docker-local/café/1.0/manifest.json", "docker-local/caf%C3%A9/1.0/manifest.json"},
+		{"docker-local/v2/app/referrers/sha256:abc", "docker-local/v2/app/referrers/sha256:abc"},
+	}
+	for _, tt := range tests {
+		if got := escapeItemPath(tt.itemPath); got != tt.want {
+			t.Errorf("escapeItemPath(%q) = %q, want %q", tt.itemPath, got, tt.want)
+		}
+	}
+}
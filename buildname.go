@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// buildNameSpecialChars are characters Artifactory treats specially in build
+// names and numbers: they break the UI links and search of the build.
+const buildNameSpecialChars = `/\:|*?"<>`
+
+// checkBuildIdentifiers validates the build name and number, applying the
+// configured transform first. Without a transform, names containing special
+// characters are rejected rather than published to a build that cannot be
+// reached in the UI.
+func checkBuildIdentifiers(args *Args) error {
+	switch args.BuildNameTransform {
+	case "":
+	case "replace":
+		args.BuildName = replaceBuildNameChars(args.BuildName)
+		args.BuildNumber = replaceBuildNameChars(args.BuildNumber)
+	case "slug":
+		args.BuildName = strings.ToLower(replaceBuildNameChars(args.BuildName))
+		args.BuildNumber = strings.ToLower(replaceBuildNameChars(args.BuildNumber))
+	default:
+		return fmt.Errorf("unsupported build name transform %q, expected replace or slug", args.BuildNameTransform)
+	}
+
+	if err := validateBuildIdentifier("build name", args.BuildName); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidSettings, err)
+	}
+	if err := validateBuildIdentifier("build number", args.BuildNumber); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidSettings, err)
+	}
+	return nil
+}
+
+// validateBuildIdentifier returns an error naming the first character of
+// value that Artifactory cannot handle in a build name or number.
+func validateBuildIdentifier(kind, value string) error {
+	if strings.TrimSpace(value) != value {
+		return fmt.Errorf("%s %q has leading or trailing whitespace", kind, value)
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return fmt.Errorf("%s %q contains control character %q", kind, value, r)
+		}
+	}
+	for _, r := range value {
+		if isBuildNameSpecialChar(r) {
+			return fmt.Errorf("%s %q contains unsupported character %q, set PLUGIN_BUILD_NAME_TRANSFORM to replace it", kind, value, r)
+		}
+	}
+	return nil
+}
+
+// replaceBuildNameChars replaces each unsupported character with a dash,
+// collapsing runs of them and trimming them from both ends.
+func replaceBuildNameChars(value string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.TrimSpace(value) {
+		if isBuildNameSpecialChar(r) {
+			if !dash {
+				b.WriteRune('-')
+				dash = true
+			}
+			continue
+		}
+		b.WriteRune(r)
+		dash = false
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+func isBuildNameSpecialChar(r rune) bool {
+	return r > unicode.MaxASCII || unicode.IsControl(r) || strings.ContainsRune(buildNameSpecialChars, r)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/sirupsen/logrus"
+)
+
+// cleanupBuild removes what a failed run left behind so a rerun starts clean:
+// the local build partials the jfrog CLI collected and, with cleanup mode
+// "build", the build if it was already published.
+func cleanupBuild(ctx context.Context, args Args, sanitizedURL string, published bool) error {
+	logrus.Info("Cleaning up the failed build")
+	cmdArgs := appendProjectFlag([]string{"jfrog", "rt", "build-clean", args.BuildName, args.BuildNumber}, args)
+	if err := runCommand(cmdArgs); err != nil {
+		return fmt.Errorf("error executing jfrog rt build-clean command: %w", err)
+	}
+	if args.CleanupOnFailure != "build" || !published {
+		return nil
+	}
+
+	// Only the build is deleted, the image artifacts are kept
+	query := url.Values{"buildNumbers": {args.BuildNumber}, "artifacts": {"0"}}
+	req, err := newRequest(ctx, http.MethodDelete, buildAPIURL(sanitizedURL, args, query, args.BuildName), nil, args)
+	if err != nil {
+		return err
+	}
+	if _, err := doRequest(req, args); err != nil {
+		return fmt.Errorf("error deleting build %s/%s: %w", args.BuildName, args.BuildNumber, err)
+	}
+	logrus.Infof("Deleted build %s/%s", args.BuildName, args.BuildNumber)
+	return nil
+}
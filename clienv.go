@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+)
+
+// prepareCLIHome points the jfrog CLI at its own home and temp directories for
+// the run, so concurrent steps on the same runner do not share configuration
+// or build partials, which the CLI keeps in its temp directory.
+// PLUGIN_CLI_HOME_DIR or JFROG_CLI_HOME_DIR select a persistent home directory
+// instead, for caching, and JFROG_CLI_TEMP_DIR a temp directory. The returned
+// function removes the per-run directories.
+func prepareCLIHome(args Args) (func(), error) {
+	if homeDir := firstNonEmpty(args.CLIHomeDir, os.Getenv("JFROG_CLI_HOME_DIR")); homeDir != "" {
+		if err := os.MkdirAll(homeDir, 0o700); err != nil {
+			return nil, fmt.Errorf("error creating jfrog CLI home directory: %w", err)
+		}
+		if err := os.Setenv("JFROG_CLI_HOME_DIR", homeDir); err != nil {
+			return nil, err
+		}
+		// Keep the build partials with the home directory for steps
+		// publishing in a later step
+		if args.SkipPublish || args.Mode == "publish" || args.ParentBuildName != "" {
+			return func() {}, setCLITempDir(filepath.Join(homeDir, "tmp"))
+		}
+		tempDir, err := os.MkdirTemp("", "jfrog-cli-temp-")
+		if err != nil {
+			return nil, fmt.Errorf("error creating jfrog CLI temp directory: %w", err)
+		}
+		return func() { removeCLIDir(tempDir) }, setCLITempDir(tempDir)
+	}
+
+	homeDir, err := os.MkdirTemp("", "jfrog-cli-home-")
+	if err != nil {
+		return nil, fmt.Errorf("error creating jfrog CLI home directory: %w", err)
+	}
+	logrus.Debugf("Using jfrog CLI home directory %s", homeDir)
+	cleanup := func() { removeCLIDir(homeDir) }
+	if err := os.Setenv("JFROG_CLI_HOME_DIR", homeDir); err != nil {
+		return cleanup, err
+	}
+	return cleanup, setCLITempDir(filepath.Join(homeDir, "tmp"))
+}
+
+// setCLITempDir points the jfrog CLI at the temp directory, unless
+// JFROG_CLI_TEMP_DIR already selects one.
+func setCLITempDir(tempDir string) error {
+	if os.Getenv("JFROG_CLI_TEMP_DIR") != "" {
+		return nil
+	}
+	if err := os.MkdirAll(tempDir, 0o700); err != nil {
+		return fmt.Errorf("error creating jfrog CLI temp directory: %w", err)
+	}
+	logrus.Debugf("Using jfrog CLI temp directory %s", tempDir)
+	return os.Setenv("JFROG_CLI_TEMP_DIR", tempDir)
+}
+
+func removeCLIDir(dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		logrus.Warnf("error removing jfrog CLI directory %s: %v", dir, err)
+	}
+}
+
+// configureCLIUsageReport sets whether the jfrog CLI reports usage statistics
+// to JFrog, for the commands the plugin runs.
+func configureCLIUsageReport(args Args) error {
+	return os.Setenv("JFROG_CLI_REPORT_USAGE", strconv.FormatBool(args.CLIReportUsage))
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// resolveAPICompat sets args.APICompat to the major version of the Artifactory
+// REST API to target, detected from the server version unless it is set.
+// Detection failures are not fatal: the current API is assumed.
+func resolveAPICompat(ctx context.Context, args *Args, sanitizedURL string) {
+	if args.APICompat != "" {
+		return
+	}
+	args.APICompat = "7"
+
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+"api/system/version", nil, *args)
+	if err != nil {
+		logrus.Warnf("Could not detect the Artifactory version: %v", err)
+		return
+	}
+	body, err := doRequest(req, *args)
+	if err != nil {
+		logrus.Warnf("Could not detect the Artifactory version: %v", err)
+		return
+	}
+	var info struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		logrus.Warnf("Could not detect the Artifactory version: %v", err)
+		return
+	}
+	if major, _, _ := strings.Cut(info.Version, "."); major == "6" || major == "5" {
+		logrus.Infof("Artifactory %s detected, using the 6.x REST API", info.Version)
+		args.APICompat = "6"
+	}
+}
+
+// legacyAPI reports whether the server predates Artifactory 7, which has no
+// projects, no build-info repositories and no access token API.
+func legacyAPI(args Args) bool {
+	return args.APICompat == "6"
+}
+
+// checkAPICompat returns an error for settings the targeted API does not support.
+func checkAPICompat(args Args) error {
+	if legacyAPI(args) && buildInfoProject(args) != "" {
+		return fmt.Errorf("build-info repository %s needs Artifactory 7 or later", args.BuildInfoRepo)
+	}
+	return nil
+}
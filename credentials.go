@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// loadCredentialsDir fills in any credentials that were not set explicitly from
+// files in a mounted secret directory. Each key is read from a file of the same
+// name, matching the layout Kubernetes uses when projecting a secret as a volume.
+func loadCredentialsDir(args *Args) error {
+	if args.CredentialsDir == "" {
+		return nil
+	}
+
+	files := []struct {
+		name  string
+		value *string
+	}{
+		{"username", &args.Username},
+		{"password", &args.Password},
+		{"token", &args.AccessToken},
+	}
+
+	for _, file := range files {
+		if *file.value != "" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(args.CredentialsDir, file.name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s from credentials directory: %w", file.name, err)
+		}
+		*file.value = strings.TrimSpace(string(content))
+		logrus.Debugf("Loaded %s from credentials directory %s", file.name, args.CredentialsDir)
+	}
+	return nil
+}
+
+// loadNetrc uses the login and password from a .netrc entry for the Artifactory
+// host when no other credentials were supplied. A "default" entry is used when
+// no machine entry matches.
+func loadNetrc(args *Args) error {
+	if args.AccessToken != "" || args.APIKey != "" || (args.Username != "" && args.Password != "") {
+		return nil
+	}
+
+	path := args.NetrcFile
+	if path == "" {
+		path = os.Getenv("NETRC")
+	}
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading netrc file %s: %w", path, err)
+	}
+
+	parsedURL, err := url.Parse(args.URL)
+	if err != nil {
+		return nil
+	}
+
+	login, password, found := findNetrcEntry(string(content), parsedURL.Hostname())
+	if !found {
+		return nil
+	}
+	args.Username, args.Password = login, password
+	logrus.Debugf("Using credentials for %s from %s", parsedURL.Hostname(), path)
+	return nil
+}
+
+// findNetrcEntry returns the login and password for host from netrc content.
+func findNetrcEntry(content, host string) (login, password string, found bool) {
+	type entry struct {
+		machine, login, password string
+		isDefault                bool
+	}
+
+	var entries []entry
+	tokens := strings.Fields(content)
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			if i+1 < len(tokens) {
+				i++
+				entries = append(entries, entry{machine: tokens[i]})
+			}
+		case "default":
+			entries = append(entries, entry{isDefault: true})
+		case "macdef":
+			// Macro bodies are free-form, so stop parsing at the first one
+			i = len(tokens)
+		case "login", "password", "account":
+			if i+1 < len(tokens) && len(entries) > 0 {
+				i++
+				current := &entries[len(entries)-1]
+				if tokens[i-1] == "login" {
+					current.login = tokens[i]
+				} else if tokens[i-1] == "password" {
+					current.password = tokens[i]
+				}
+			}
+		}
+	}
+
+	for _, e := range entries {
+		if !e.isDefault && e.machine == host {
+			return e.login, e.password, true
+		}
+	}
+	for _, e := range entries {
+		if e.isDefault {
+			return e.login, e.password, true
+		}
+	}
+	return "", "", false
+}
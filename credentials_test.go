@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestFindNetrcEntry(t *testing.T) {
+	const content = `
+machine acme.jfrog.io
+  login ci
+  password s3cret
+machine other.example.com login other password pw account acct
+default login anonymous password guest
+`
+	tests := []struct {
+		name         string
+		content      string
+		host         string
+		wantLogin    string
+		wantPassword string
+		wantFound    bool
+	}{
+		{"machine", content, "acme.jfrog.io", "ci", "s3cret", true},
+		{"single line with account", content, "other.example.com", "other", "pw", true},
+		{"default", content, "unknown.example.com", "anonymous", "guest", true},
+		{"no default", "machine acme.jfrog.io login ci password s3cret", "unknown.example.com", "", "", false},
+		{"default before machine", "default login anonymous password guest\nmachine acme.jfrog.io login ci password s3cret", "acme.jfrog.io", "ci", "s3cret", true},
+		{"macdef ends parsing", "machine a.example.com login a password pa\nmacdef init\nmachine b.example.com login b password pb", "b.example.com", "", "", false},
+		{"missing password", "machine acme.jfrog.io login ci", "acme.jfrog.io", "ci", "", true},
+		{"empty", "", "acme.jfrog.io", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login, password, found := findNetrcEntry(tt.content, tt.host)
+			if login != tt.wantLogin || password != tt.wantPassword || found != tt.wantFound {
+				t.Errorf("findNetrcEntry(%q) = %q, %q, %v, want %q, %q, %v", tt.host, login, password, found, tt.wantLogin, tt.wantPassword, tt.wantFound)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dockerSocket is where the Docker daemon listens unless DOCKER_HOST is set.
+const dockerSocket = "/var/run/docker.sock"
+
+// dockerDaemonAvailable reports whether a Docker daemon and the docker CLI
+// can be used to inspect the local images.
+func dockerDaemonAvailable() bool {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return false
+	}
+	if os.Getenv("DOCKER_HOST") != "" {
+		return true
+	}
+	_, err := os.Stat(dockerSocket)
+	return err == nil
+}
+
+// checkLocalImage compares the digest of the manifest found in Artifactory
+// with the repo digests of the image in the local Docker daemon, to catch a
+// search that matched a stale manifest of an earlier push. Images the daemon
+// does not know are skipped with a warning.
+func checkLocalImage(img dockerImage) error {
+	ref, _, _ := strings.Cut(img.Ref, "@")
+	output, err := runCommandAndCaptureStdout([]string{"docker", "image", "inspect", "--format", "{{json .RepoDigests}}", ref})
+	if err != nil {
+		logrus.Warnf("Skipping the local image check of %s, docker image inspect failed: %v", ref, err)
+		return nil
+	}
+	var repoDigests []string
+	if err := json.Unmarshal(output, &repoDigests); err != nil {
+		return fmt.Errorf("error parsing repo digests of %s: %w", ref, err)
+	}
+
+	repository := strings.TrimSuffix(ref, ":"+img.Tag)
+	var local []string
+	for _, repoDigest := range repoDigests {
+		name, digest, _ := strings.Cut(repoDigest, "@")
+		if name != repository {
+			continue
+		}
+		if digest == img.digest() {
+			logrus.Infof("Digest of %s matches the local image", ref)
+			return nil
+		}
+		local = append(local, digest)
+	}
+	if len(local) == 0 {
+		logrus.Warnf("Skipping the local image check of %s, the local image has no digest for %s; was it pushed from this daemon?", ref, repository)
+		return nil
+	}
+	return fmt.Errorf("manifest found in Artifactory for %s has digest %s, but the local image has %s; did the search match a manifest of an earlier push?", ref, img.digest(), strings.Join(local, ", "))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+var argReference = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)\}?`)
+
+// parseBaseImages returns the images referenced by the FROM instructions of a
+// Dockerfile. References to earlier build stages and scratch are skipped, and
+// global ARG defaults are substituted into the image references.
+func parseBaseImages(content string) []string {
+	// Join continuation lines so each instruction is on a single line
+	content = strings.ReplaceAll(content, "\\\r\n", " ")
+	content = strings.ReplaceAll(content, "\\\n", " ")
+
+	globalArgs := map[string]string{}
+	stages := map[string]bool{}
+	seenFrom := false
+	var images []string
+
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "ARG":
+			// Only ARGs declared before the first FROM can be used in FROM lines
+			if seenFrom || len(fields) < 2 {
+				continue
+			}
+			name, value, _ := strings.Cut(fields[1], "=")
+			globalArgs[name] = strings.Trim(value, `"'`)
+		case "FROM":
+			seenFrom = true
+			var params []string
+			for _, field := range fields[1:] {
+				if !strings.HasPrefix(field, "--") {
+					params = append(params, field)
+				}
+			}
+			if len(params) == 0 {
+				continue
+			}
+			image := argReference.ReplaceAllStringFunc(params[0], func(ref string) string {
+				return globalArgs[argReference.FindStringSubmatch(ref)[1]]
+			})
+			isStage := stages[strings.ToLower(image)]
+			if len(params) >= 3 && strings.EqualFold(params[1], "AS") {
+				stages[strings.ToLower(params[2])] = true
+			}
+			if image == "" || image == "scratch" || isStage {
+				continue
+			}
+			images = append(images, image)
+		}
+	}
+	return images
+}
+
+// resolveBaseImageDependencies reads the base images from the Dockerfile and
+// resolves their manifests in Artifactory to build info dependencies. Base
+// images that cannot be found in Artifactory are skipped with a warning.
+func resolveBaseImageDependencies(ctx context.Context, args Args, sanitizedURL string) ([]map[string]interface{}, error) {
+	content, err := os.ReadFile(args.Dockerfile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Dockerfile: %w", err)
+	}
+
+	var dependencies []map[string]interface{}
+	for _, image := range parseBaseImages(string(content)) {
+		// Images pinned by digest are recorded as-is
+		if name, digest, found := strings.Cut(image, "@"); found {
+			dependencies = append(dependencies, map[string]interface{}{
+				"id":     name,
+				"type":   "docker",
+				"sha256": strings.TrimPrefix(digest, "sha256:"),
+			})
+			continue
+		}
+
+		if !strings.Contains(image, "/") {
+			if err := warnOrFail(args, "Skipping base image %s: it does not reference an Artifactory repository", image); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// Base images may come from other repositories, so only the registry map applies
+		host, _, _ := strings.Cut(image, "/")
+		registries, _ := parseRegistryMap(args.RegistryMap)
+		repo, imageName, imageTag, err := parseDockerImage(image, registries[host], registryHosts(args))
+		if err != nil {
+			if err := warnOrFail(args, "Skipping base image %s: %v", image, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		checksums, err := baseImageChecksums(ctx, args, sanitizedURL, repo, imageName, imageTag)
+		if err != nil {
+			if err := warnOrFail(args, "Skipping base image %s: could not resolve its manifest in Artifactory: %v", image, err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		logrus.Infof("Recording base image %s as a dependency", image)
+		dependencies = append(dependencies, map[string]interface{}{
+			"id":     imageName + ":" + imageTag,
+			"type":   "docker",
+			"sha1":   checksums.Sha1,
+			"sha256": checksums.Sha256,
+			"md5":    checksums.Md5,
+		})
+	}
+	return dependencies, nil
+}
+
+// baseImageChecksums returns the checksums of the manifest of a base image,
+// found with the same PLUGIN_MANIFEST_PATH_TEMPLATE and PLUGIN_MANIFEST_NAMES
+// search as the built images. The digest of a base image is not known, so it
+// is always searched by path.
+func baseImageChecksums(ctx context.Context, args Args, sanitizedURL, repo, imageName, imageTag string) (checksums, error) {
+	searchArgs := args
+	searchArgs.SearchMode = "path"
+	manifest, err := searchManifest(ctx, searchArgs, sanitizedURL, repo, imageName, imageTag, "")
+	if err != nil {
+		return checksums{}, err
+	}
+	return getChecksums(ctx, args, sanitizedURL, manifest.Path)
+}
+
+// dockerfileProperties returns the path of the Dockerfile, relative to the
+// repository when it is inside it, and the SHA256 of its content, to tell
+// which Dockerfile the image was built from once the repository moves on.
+func dockerfileProperties(args Args) (map[string]string, error) {
+	content, err := os.ReadFile(args.Dockerfile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Dockerfile: %w", err)
+	}
+	dockerfilePath := filepath.Clean(args.Dockerfile)
+	if filepath.IsAbs(dockerfilePath) && args.GitPath != "" {
+		if rel, err := filepath.Rel(args.GitPath, dockerfilePath); err == nil && !strings.HasPrefix(rel, "..") {
+			dockerfilePath = rel
+		}
+	}
+	sum := sha256.Sum256(content)
+	return map[string]string{
+		"docker.dockerfile.path":   filepath.ToSlash(dockerfilePath),
+		"docker.dockerfile.sha256": hex.EncodeToString(sum[:]),
+	}, nil
+}
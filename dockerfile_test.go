@@ -0,0 +1,34 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBaseImages(t *testing.T) {
+	tests := []struct {
+		name       string
+		dockerfile string
+		want       []string
+	}{
+		{"single", "FROM alpine:3.19\nRUN apk add curl", []string{"alpine:3.19"}},
+		{"lowercase", "from alpine:3.19", []string{"alpine:3.19"}},
+		{"platform flag", "FROM --platform=linux/amd64 golang:1.22 AS build", []string{"golang:1.22"}},
+		{"multi-stage", "FROM golang:1.22 AS build\nFROM build AS test\nFROM gcr.io/distroless/static\nCOPY --from=build /app /app", []string{"golang:1.22", "gcr.io/distroless/static"}},
+		{"stage name case", "FROM golang:1.22 AS Build\nFROM build", []string{"golang:1.22"}},
+		{"scratch", "FROM golang:1.22 AS build\nFROM scratch", []string{"golang:1.22"}},
+		{"global arg", "ARG BASE=alpine\nARG VERSION=\"3.19\"\nFROM ${BASE}:$VERSION", []string{"alpine:3.19"}},
+		{"arg after from", "FROM alpine:3.19\nARG BASE=debian\nFROM $BASE", []string{"alpine:3.19"}},
+		{"unset arg", "FROM $BASE", nil},
+		{"continuation", "FROM \\\n  registry.example.com/base:1 \\\n  AS base\nFROM base", []string{"registry.example.com/base:1"}},
+		{"comments", "# FROM commented:1\nFROM alpine:3.19", []string{"alpine:3.19"}},
+		{"digest", "FROM alpine@sha256:abc", []string{"alpine@sha256:abc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseBaseImages(tt.dockerfile); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseBaseImages() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+)
+
+// buildEnvPrefix is the property prefix of the build info environment section,
+// as build-collect-env records it.
+const buildEnvPrefix = "buildInfo.env."
+
+// envFileProperties reads a dotenv file and returns its entries as the
+// environment section of the build info. Blank lines, comments and an "export"
+// prefix are allowed, and values may be quoted.
+func envFileProperties(envFile string) (map[string]string, error) {
+	content, err := os.ReadFile(envFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading env file: %w", err)
+	}
+
+	properties := map[string]string{}
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid env file %s line %d: expected KEY=VALUE", envFile, i+1)
+		}
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid env file %s line %d: %w", envFile, i+1, err)
+			}
+			value = unquoted
+		} else if len(value) > 1 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+			value = value[1 : len(value)-1]
+		}
+		properties[buildEnvPrefix+key] = value
+	}
+	return properties, nil
+}
+
+// secretKeyParts are the parts of variable names that mark secrets, matched
+// case-insensitively anywhere in the name like the *password*, *secret*,
+// *key*, *token* and *auth* env-exclude patterns of the jfrog CLI, so
+// DBPASSWORD, apiToken and DB_PASS are all secrets.
+var secretKeyParts = []string{"pass", "psw", "secret", "key", "token", "auth", "credential", "private"}
+
+// isSecretName reports whether a variable name contains a part that marks secrets.
+func isSecretName(name string) bool {
+	name = strings.ToLower(name)
+	for _, part := range secretKeyParts {
+		if strings.Contains(name, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSecrets replaces the values of environment properties that look like
+// secrets, by the variable name or by a high-entropy value, so they are not
+// stored in the build info.
+func redactSecrets(properties map[string]string) {
+	var redacted []string
+	for property, value := range properties {
+		secret := isHighEntropy(value) || isSecretName(strings.TrimPrefix(property, buildEnvPrefix))
+		if secret && value != "" {
+			properties[property] = "***"
+			redacted = append(redacted, strings.TrimPrefix(property, buildEnvPrefix))
+		}
+	}
+	if len(redacted) > 0 {
+		sort.Strings(redacted)
+		logrus.Warnf("Redacted environment variables that look like secrets: %s", strings.Join(redacted, ", "))
+	}
+}
+
+// isHighEntropy reports whether a value is long and random enough to be a
+// generated secret such as an API token. Besides values above an absolute
+// threshold, mixed letters and digits count when their entropy is close to
+// the most a value of their length can have, so 20 character keys such as AWS
+// access key IDs are caught. Hex digests, paths and URLs stay below both.
+func isHighEntropy(value string) bool {
+	if len(value) < 20 || strings.ContainsAny(value, " \t\r\n") {
+		return false
+	}
+	counts := map[rune]int{}
+	var letters, digits, alphanumeric int
+	for _, r := range value {
+		counts[r]++
+		switch {
+		case unicode.IsLetter(r):
+			letters++
+			alphanumeric++
+		case unicode.IsDigit(r):
+			digits++
+			alphanumeric++
+		}
+	}
+	var entropy float64
+	for _, count := range counts {
+		p := float64(count) / float64(len(value))
+		entropy -= p * math.Log2(p)
+	}
+	if entropy > 4.5 {
+		return true
+	}
+	mostlyAlphanumeric := alphanumeric*10 >= len(value)*9
+	return letters > 0 && digits > 0 && mostlyAlphanumeric && entropy/math.Log2(float64(len(value))) >= 0.8
+}
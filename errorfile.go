@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// errInvalidSettings is returned when the settings fail validation.
+var errInvalidSettings = errors.New("invalid settings")
+
+// phaseError is an error returned by a phase of the run, recording the phase.
+type phaseError struct {
+	phase string
+	err   error
+}
+
+func (e *phaseError) Error() string { return e.err.Error() }
+
+func (e *phaseError) Unwrap() error { return e.err }
+
+// errorReport is the failure summary written to PLUGIN_ERROR_FILE, for the CI
+// system to show instead of the log.
+type errorReport struct {
+	Category string `json:"category"`
+	Message  string `json:"message"`
+	Phase    string `json:"phase,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+}
+
+// newErrorReport categorizes the error of the run.
+func newErrorReport(err error, args Args) errorReport {
+	report := errorReport{Category: "error", Message: err.Error(), Hint: errorHint(err, args)}
+	var phaseErr *phaseError
+	if errors.As(err, &phaseErr) {
+		report.Phase = phaseErr.phase
+	}
+
+	statusCode := errorStatusCode(err)
+	switch {
+	case errors.Is(err, errInvalidSettings):
+		report.Category = "configuration"
+		report.Hint = firstNonEmpty(report.Hint, "fix the settings listed in the message")
+	case errors.Is(err, errManifestNotFound):
+		report.Category = "image-not-found"
+		report.Hint = firstNonEmpty(report.Hint, "check that the image was pushed to the repository before this step, with the tag given in docker_image")
+	case statusCode == http.StatusUnauthorized:
+		report.Category = "authentication"
+	case statusCode == http.StatusForbidden:
+		report.Category = "permission"
+	case statusCode == http.StatusNotFound:
+		report.Category = "not-found"
+	case statusCode == http.StatusConflict:
+		report.Category = "conflict"
+	}
+	return report
+}
+
+// writeErrorFile writes the failure summary of the run as JSON.
+func writeErrorFile(errorFile string, report errorReport) error {
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding error file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(errorFile), 0o755); err != nil {
+		return fmt.Errorf("error creating error file directory: %w", err)
+	}
+	if err := os.WriteFile(errorFile, append(body, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing error file: %w", err)
+	}
+	return nil
+}
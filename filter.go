@@ -0,0 +1,58 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// splitPatterns returns the patterns of a comma separated list.
+func splitPatterns(list string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(list, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// matchesAny reports whether the name matches one of the glob patterns.
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// filterModuleArtifacts returns an edit that keeps only the artifacts of the
+// module whose names match one of the include patterns, when there are any,
+// and none of the exclude patterns, to keep the build info of images with
+// many layers small.
+func filterModuleArtifacts(moduleID string, include, exclude []string) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		module := dockerModule(buildInfo, moduleID)
+		if module == nil {
+			logrus.Warnf("Skipping the artifact filter, the build info has no docker module %s", moduleID)
+			return nil
+		}
+		artifacts, _ := module["artifacts"].([]interface{})
+		var kept []interface{}
+		for _, a := range artifacts {
+			artifact, _ := a.(map[string]interface{})
+			name, _ := artifact["name"].(string)
+			if len(include) > 0 && !matchesAny(name, include) || matchesAny(name, exclude) {
+				continue
+			}
+			kept = append(kept, a)
+		}
+		if removed := len(artifacts) - len(kept); removed > 0 {
+			logrus.Infof("Filtered %d of %d artifacts out of module %s", removed, len(artifacts), moduleID)
+		}
+		module["artifacts"] = kept
+		return nil
+	}
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// checkFIPS fails the step when PLUGIN_FIPS requires FIPS mode and the plugin
+// was not built with BoringCrypto, so a non-compliant binary is never used for
+// the TLS connections to Artifactory.
+func checkFIPS(args Args) error {
+	if !args.FIPS {
+		return nil
+	}
+	if !fipsEnabled() {
+		return errors.New("FIPS mode is required but the plugin was not built with BoringCrypto, build it with FIPS=true scripts/build.sh")
+	}
+	logrus.Info("FIPS mode enabled, TLS is restricted to FIPS-approved settings")
+	return nil
+}
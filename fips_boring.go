@@ -0,0 +1,15 @@
+//go:build goexperiment.boringcrypto
+
+package main
+
+import (
+	"crypto/boring"
+
+	// Restrict TLS to FIPS-approved versions, cipher suites and curves
+	_ "crypto/tls/fipsonly"
+)
+
+// fipsEnabled reports whether crypto operations use the BoringCrypto module.
+func fipsEnabled() bool {
+	return boring.Enabled()
+}
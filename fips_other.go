@@ -0,0 +1,8 @@
+//go:build !goexperiment.boringcrypto
+
+package main
+
+// fipsEnabled reports whether crypto operations use the BoringCrypto module.
+func fipsEnabled() bool {
+	return false
+}
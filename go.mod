@@ -3,7 +3,9 @@ module drone-artifactory-docker-buildinfo
 go 1.22.5
 
 require (
-	github.com/kelseyhightower/envconfig v1.4.0 // indirect
-	github.com/sirupsen/logrus v1.9.3 // indirect
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/sirupsen/logrus v1.9.3
+	gopkg.in/yaml.v3 v3.0.1
 )
+
+require golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// healthResult is the outcome of a single healthcheck.
+type healthResult struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Detail   string `json:"detail"`
+	Duration string `json:"duration"`
+}
+
+// Healthcheck verifies that Artifactory is reachable, that the credentials are
+// accepted and that the jfrog CLI is usable. The results are printed as JSON and
+// an error is returned if any check failed.
+func Healthcheck(ctx context.Context, args Args, sanitizedURL string) error {
+	checks := []struct {
+		name string
+		fn   func() (string, error)
+	}{
+		{"ping", func() (string, error) { return checkPing(ctx, args, sanitizedURL) }},
+		{"authentication", func() (string, error) { return checkAuthentication(ctx, args, sanitizedURL) }},
+		{"jfrog-cli", checkCLI},
+	}
+
+	var results []healthResult
+	failed := false
+	for _, check := range checks {
+		start := time.Now()
+		detail, err := check.fn()
+		result := healthResult{Name: check.name, OK: err == nil, Detail: detail, Duration: time.Since(start).Round(time.Millisecond).String()}
+		if err != nil {
+			result.Detail = err.Error()
+			failed = true
+			logrus.Errorf("Healthcheck %s failed: %v", check.name, err)
+		} else {
+			logrus.Infof("Healthcheck %s passed: %s", check.name, detail)
+		}
+		results = append(results, result)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(results); err != nil {
+		return fmt.Errorf("error encoding healthcheck results: %w", err)
+	}
+
+	if failed {
+		return errors.New("one or more healthchecks failed")
+	}
+	return nil
+}
+
+// checkPing calls the system ping endpoint, which needs no credentials but may
+// need the PLUGIN_HTTP_HEADERS headers to get through a proxy.
+func checkPing(ctx context.Context, args Args, sanitizedURL string) (string, error) {
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+"api/system/ping", nil, args)
+	if err != nil {
+		return "", err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// checkAuthentication requests the server version with the configured
+// credentials, which Artifactory rejects with 401 when they are invalid.
+func checkAuthentication(ctx context.Context, args Args, sanitizedURL string) (string, error) {
+	if args.AccessToken == "" && args.APIKey == "" && (args.Username == "" || args.Password == "") {
+		return "", errors.New("either username/password, api key or access token needs to be set")
+	}
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+"api/system/version", nil, args)
+	if err != nil {
+		return "", err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return "", err
+	}
+
+	var info struct {
+		Version string `json:"version"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("error parsing version response: %w", err)
+	}
+	return "Artifactory " + info.Version, nil
+}
+
+// checkCLI verifies that the jfrog CLI can be executed.
+func checkCLI() (string, error) {
+	output, err := exec.Command("jfrog", "--version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("error executing jfrog --version: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+)
+
+// helmChartModule returns a build-info module for the Helm chart package in the
+// chart repository, so the chart is published in the same build as the image.
+func helmChartModule(ctx context.Context, args Args, sanitizedURL string) (map[string]interface{}, error) {
+	chartPath := firstNonEmpty(args.HelmChartPath, args.HelmChartName+"-"+args.HelmChartVersion+".tgz")
+	sums, err := getChecksums(ctx, args, sanitizedURL, args.HelmRepo+"/"+chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Helm chart %s/%s: %w", args.HelmRepo, chartPath, err)
+	}
+
+	return map[string]interface{}{
+		// Helm is not a build-info module type, charts are recorded as generic modules
+		"type": "generic",
+		"id":   args.HelmChartName + ":" + args.HelmChartVersion,
+		"properties": map[string]interface{}{
+			"helm.chart.name":    args.HelmChartName,
+			"helm.chart.version": args.HelmChartVersion,
+		},
+		"artifacts": []interface{}{
+			map[string]interface{}{
+				"type":                   "tgz",
+				"name":                   path.Base(chartPath),
+				"path":                   chartPath,
+				"originalDeploymentRepo": args.HelmRepo,
+				"sha1":                   sums.Sha1,
+				"sha256":                 sums.Sha256,
+				"md5":                    sums.Md5,
+			},
+		},
+	}, nil
+}
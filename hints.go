@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+)
+
+// cliStatus matches the HTTP status the jfrog CLI prints when Artifactory
+// rejects a request.
+var cliStatus = regexp.MustCompile(`\b(401 Unauthorized|403 Forbidden|404 Not Found|409 Conflict)\b`)
+
+// commandError is a failed jfrog CLI command, with the HTTP status Artifactory
+// answered with when the output shows one.
+type commandError struct {
+	err        error
+	statusCode int
+}
+
+func (e *commandError) Error() string { return e.err.Error() }
+
+func (e *commandError) Unwrap() error { return e.err }
+
+// commandFailure returns the error of a failed jfrog CLI command, recording the
+// HTTP status found in its output.
+func commandFailure(err error, output []byte) error {
+	match := cliStatus.FindSubmatch(output)
+	if match == nil {
+		return err
+	}
+	statusCode, _ := strconv.Atoi(string(match[1][:3]))
+	return &commandError{err: err, statusCode: statusCode}
+}
+
+// errorStatusCode returns the HTTP status an error was caused by, from either
+// the REST API or the jfrog CLI, or 0 when there is none.
+func errorStatusCode(err error) int {
+	var httpErr *httpError
+	var cmdErr *commandError
+	switch {
+	case errors.As(err, &httpErr):
+		return httpErr.StatusCode
+	case errors.As(err, &cmdErr):
+		return cmdErr.statusCode
+	}
+	return 0
+}
+
+// errorHint returns guidance for the HTTP status an error was caused by, or ""
+// when there is none.
+func errorHint(err error, args Args) string {
+	switch errorStatusCode(err) {
+	case http.StatusUnauthorized:
+		return "Artifactory rejected the credentials: check that the access token, API key or password is valid and has not expired"
+	case http.StatusForbidden:
+		return fmt.Sprintf("the user lacks a permission: check that the token scope covers Artifactory, and that the user can read and annotate the image repository and deploy to the build-info repository %s", buildInfoRepo(args))
+	case http.StatusNotFound:
+		return fmt.Sprintf("Artifactory did not find a resource: check that PLUGIN_URL points to the /artifactory context, that the image repository exists, and that the build-info repository %s exists", buildInfoRepo(args))
+	case http.StatusConflict:
+		return "Artifactory reported a conflict: a build with this name and number may already be published to another project, or the target already exists"
+	}
+	return ""
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// dockerImage is one of the images the build is created for.
+type dockerImage struct {
+	Ref      string
+	Repo     string
+	Name     string
+	Tag      string
+	Manifest Artifact
+
+	// RefDigest is the digest the image reference is pinned to, if any
+	RefDigest string
+
+	// ModuleIDOverride and Properties are the settings of the image in
+	// PLUGIN_IMAGE_SETTINGS
+	ModuleIDOverride string
+	Properties       map[string]string
+
+	// manifest is the downloaded manifest, when a feature needs its contents
+	manifest *imageManifest
+}
+
+// imageSetting is the entry of an image in PLUGIN_IMAGE_SETTINGS.
+type imageSetting struct {
+	ModuleID   string            `json:"module_id"`
+	Properties map[string]string `json:"properties"`
+}
+
+// moduleID returns the ID of the module the image is recorded in.
+func (img dockerImage) moduleID() string {
+	return firstNonEmpty(img.ModuleIDOverride, img.cliModuleID())
+}
+
+// cliModuleID returns the ID of the module build-docker-create records the
+// image in: the last segment of the image name with the tag.
+func (img dockerImage) cliModuleID() string {
+	return path.Base(img.Name) + ":" + img.Tag
+}
+
+// digest returns the digest of the image manifest.
+func (img dockerImage) digest() string {
+	if img.Manifest.Sha256 == "" {
+		return ""
+	}
+	return "sha256:" + img.Manifest.Sha256
+}
+
+// readImageListFile returns the image references listed in the file, one per
+// line, as a comma separated list. Blank lines and # comments are skipped.
+func readImageListFile(listFile string) (string, error) {
+	content, err := os.ReadFile(listFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading image list file: %w", err)
+	}
+	var refs []string
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		refs = append(refs, line)
+	}
+	return strings.Join(refs, ","), nil
+}
+
+// parseDockerImages parses the comma separated list of images to create the
+// build for.
+func parseDockerImages(list string, args Args) ([]dockerImage, error) {
+	settings, err := parseImageSettings(args.ImageSettings)
+	if err != nil {
+		return nil, err
+	}
+	var images []dockerImage
+	for _, ref := range strings.Split(list, ",") {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		// Images may be pinned to a digest as <image>:<tag>@sha256:<hex>
+		name, refDigest, _ := strings.Cut(ref, "@")
+		repo, name, tag, err := parseDockerImage(name, imageRepoKey(args, name), registryHosts(args))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing Docker image %s: %w", ref, err)
+		}
+		setting := settings[ref]
+		images = append(images, dockerImage{Ref: ref, Repo: repo, Name: name, Tag: tag, RefDigest: refDigest, ModuleIDOverride: setting.ModuleID, Properties: setting.Properties})
+	}
+	return images, nil
+}
+
+// forEachImage runs fn for every image with at most concurrency calls running
+// at once, and returns the errors of all failed calls.
+func forEachImage(images []dockerImage, concurrency int, fn func(img *dockerImage) error) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	errs := make([]error, len(images))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range images {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(&images[i]); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", images[i].Ref, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// imageRepoKey returns the repository key of an image set by PLUGIN_REPO_KEY or
+// mapped from its registry host by PLUGIN_REGISTRY_MAP, or "" to guess it from
+// the image path.
+func imageRepoKey(args Args, image string) string {
+	if args.RepoKey != "" {
+		return args.RepoKey
+	}
+	host, _, _ := strings.Cut(image, "/")
+	registries, _ := parseRegistryMap(args.RegistryMap)
+	return registries[host]
+}
+
+// parseImageSettings parses the JSON object of per-image settings, keyed by the
+// image reference as given in docker_image.
+func parseImageSettings(value string) (map[string]imageSetting, error) {
+	settings := map[string]imageSetting{}
+	if strings.TrimSpace(value) == "" {
+		return settings, nil
+	}
+	if err := json.Unmarshal([]byte(value), &settings); err != nil {
+		return nil, fmt.Errorf("error parsing image settings: %w", err)
+	}
+	return settings, nil
+}
+
+// parseRegistryMap parses a comma separated list of registry=repository pairs.
+func parseRegistryMap(value string) (map[string]string, error) {
+	registries := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		host, repo, found := strings.Cut(pair, "=")
+		host, repo = strings.TrimSpace(host), strings.TrimSpace(repo)
+		if !found || host == "" || repo == "" {
+			return nil, fmt.Errorf("invalid registry mapping %q, expected <registry>=<repository>", pair)
+		}
+		registries[host] = repo
+	}
+	return registries, nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// issuesServerID is the jfrog CLI server configuration used for issue collection.
+const issuesServerID = "drone-buildinfo"
+
+// issuesConfigEnabled reports whether issues should be collected with build-add-git.
+func issuesConfigEnabled(args Args) bool {
+	return args.IssuesConfig != "" || args.IssuesRegexp != ""
+}
+
+// prepareIssuesConfig returns the path of the issues collection config for
+// build-add-git, writing one from the plugin settings unless a file is given.
+// Issue collection reads the previous build from Artifactory through a
+// configured server, so one is added to the jfrog CLI configuration as well.
+func prepareIssuesConfig(args Args, sanitizedURL string) (string, error) {
+	if err := configureServer(args, sanitizedURL); err != nil {
+		return "", err
+	}
+	if args.IssuesConfig != "" {
+		return args.IssuesConfig, nil
+	}
+
+	lines := []string{
+		"version: 1",
+		"issues:",
+		"  serverID: " + issuesServerID,
+		"  trackerName: " + strconv.Quote(args.IssuesTrackerName),
+		"  regexp: " + strconv.Quote(args.IssuesRegexp),
+		"  keyGroupIndex: " + strconv.Itoa(args.IssuesKeyGroupIndex),
+		"  summaryGroupIndex: " + strconv.Itoa(args.IssuesSummaryGroupIndex),
+		"  aggregate: " + strconv.FormatBool(args.IssuesAggregate),
+	}
+	if args.IssuesTrackerURL != "" {
+		lines = append(lines, "  trackerUrl: "+strconv.Quote(args.IssuesTrackerURL))
+	}
+	if args.IssuesAggregationStatus != "" {
+		lines = append(lines, "  aggregationStatus: "+strconv.Quote(args.IssuesAggregationStatus))
+	}
+
+	configFile, err := os.CreateTemp("", "issues-*.yaml")
+	if err != nil {
+		return "", fmt.Errorf("error creating issues config: %w", err)
+	}
+	defer configFile.Close()
+	if _, err := configFile.WriteString(strings.Join(lines, "\n") + "\n"); err != nil {
+		return "", fmt.Errorf("error writing issues config: %w", err)
+	}
+	return configFile.Name(), nil
+}
+
+// configureServer adds the Artifactory server to the jfrog CLI configuration
+// for commands that only accept a server ID.
+func configureServer(args Args, sanitizedURL string) error {
+	cmdArgs := []string{"jfrog", "config", "add", issuesServerID, "--artifactory-url=" + sanitizedURL, "--interactive=false", "--overwrite"}
+	if args.Username != "" && args.Password != "" {
+		cmdArgs = append(cmdArgs, "--user="+args.Username, "--password="+args.Password)
+	} else if args.APIKey != "" && args.Username != "" {
+		// API keys are accepted in place of the password
+		cmdArgs = append(cmdArgs, "--user="+args.Username, "--password="+args.APIKey)
+	} else if args.AccessToken != "" {
+		cmdArgs = append(cmdArgs, "--access-token="+args.AccessToken)
+	} else {
+		return errors.New("the jfrog CLI server configuration needs username/password, username/api key or an access token")
+	}
+	if err := runCommand(cmdArgs); err != nil {
+		return fmt.Errorf("error executing jfrog config add command: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package main
+
+import "strings"
+
+// manifestSearchPath returns the path of the image manifest in the repository
+// from the PLUGIN_MANIFEST_PATH_TEMPLATE template, where {name} is the image
+// name and {tag} the tag. The default template is the <image>/<tag> layout of
+// Docker repositories.
+func manifestSearchPath(template, imageName, imageTag string) string {
+	return strings.NewReplacer("{name}", imageName, "{tag}", imageTag).Replace(template)
+}
+
+// pathCriterion returns the AQL criterion matching the path or name, as a
+// pattern when it contains wildcards.
+func pathCriterion(value string) interface{} {
+	if strings.ContainsAny(value, "*?") {
+		return map[string]interface{}{"$match": value}
+	}
+	return value
+}
+
+// manifestNameCriteria returns the AQL criteria matching any of the comma
+// separated manifest file names of PLUGIN_MANIFEST_NAMES.
+func manifestNameCriteria(names string) []map[string]interface{} {
+	var criteria []map[string]interface{}
+	for _, name := range splitPatterns(names) {
+		criteria = append(criteria, map[string]interface{}{"name": pathCriterion(name)})
+	}
+	return criteria
+}
@@ -1,42 +1,159 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
 	"os/exec"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 	"github.com/sirupsen/logrus"
 )
 
 type Args struct {
-	BuildNumber     string `envconfig:"PLUGIN_BUILD_NUMBER"`
-	BuildName       string `envconfig:"PLUGIN_BUILD_NAME"`
-	BuildURL        string `envconfig:"PLUGIN_BUILD_URL"`
-	DockerImage     string `envconfig:"PLUGIN_DOCKER_IMAGE"`
-	URL             string `envconfig:"PLUGIN_URL"`
-	AccessToken     string `envconfig:"PLUGIN_ACCESS_TOKEN"`
-	Username        string `envconfig:"PLUGIN_USERNAME"`
-	Password        string `envconfig:"PLUGIN_PASSWORD"`
-	APIKey          string `envconfig:"PLUGIN_API_KEY"`
-	Insecure        string `envconfig:"PLUGIN_INSECURE"`
-	PEMFileContents string `envconfig:"PLUGIN_PEM_FILE_CONTENTS"`
-	PEMFilePath     string `envconfig:"PLUGIN_PEM_FILE_PATH"`
-	Level           string `envconfig:"PLUGIN_LOG_LEVEL"`
-	GitPath         string `envconfig:"PLUGIN_GIT_PATH"`
-	CommitSha       string `envconfig:"DRONE_COMMIT_SHA"`
-	RepoURL         string `envconfig:"DRONE_GIT_HTTP_URL"`
-	BranchName      string `envconfig:"DRONE_REPO_BRANCH"`
-	CommitMessage   string `envconfig:"DRONE_COMMIT_MESSAGE"`
-	DefaultPath     string `envconfig:"DRONE_WORKSPACE"`
+	BuildNumber             string `envconfig:"PLUGIN_BUILD_NUMBER"`
+	BuildName               string `envconfig:"PLUGIN_BUILD_NAME"`
+	BuildURL                string `envconfig:"PLUGIN_BUILD_URL"`
+	BuildLink               string `envconfig:"DRONE_BUILD_LINK"`
+	RepoName                string `envconfig:"DRONE_REPO_NAME"`
+	DroneBuildNumber        string `envconfig:"DRONE_BUILD_NUMBER"`
+	BuildNameTransform      string `envconfig:"PLUGIN_BUILD_NAME_TRANSFORM"`
+	DockerImage             string `envconfig:"PLUGIN_DOCKER_IMAGE"`
+	URL                     string `envconfig:"PLUGIN_URL"`
+	AccessToken             string `envconfig:"PLUGIN_ACCESS_TOKEN"`
+	Username                string `envconfig:"PLUGIN_USERNAME"`
+	Password                string `envconfig:"PLUGIN_PASSWORD"`
+	APIKey                  string `envconfig:"PLUGIN_API_KEY"`
+	Insecure                string `envconfig:"PLUGIN_INSECURE"`
+	PEMFileContents         string `envconfig:"PLUGIN_PEM_FILE_CONTENTS"`
+	PEMFilePath             string `envconfig:"PLUGIN_PEM_FILE_PATH"`
+	Level                   string `envconfig:"PLUGIN_LOG_LEVEL"`
+	GitPath                 string `envconfig:"PLUGIN_GIT_PATH"`
+	CommitSha               string `envconfig:"DRONE_COMMIT_SHA"`
+	RepoURL                 string `envconfig:"DRONE_GIT_HTTP_URL"`
+	BranchName              string `envconfig:"DRONE_REPO_BRANCH"`
+	CommitBranch            string `envconfig:"DRONE_COMMIT_BRANCH"`
+	CommitMessage           string `envconfig:"DRONE_COMMIT_MESSAGE"`
+	CommitAuthor            string `envconfig:"DRONE_COMMIT_AUTHOR"`
+	CommitAuthorName        string `envconfig:"DRONE_COMMIT_AUTHOR_NAME"`
+	CommitAuthorEmail       string `envconfig:"DRONE_COMMIT_AUTHOR_EMAIL"`
+	CommitLink              string `envconfig:"DRONE_COMMIT_LINK"`
+	DefaultPath             string `envconfig:"DRONE_WORKSPACE"`
+	CredentialsDir          string `envconfig:"PLUGIN_CREDENTIALS_DIR"`
+	NetrcFile               string `envconfig:"PLUGIN_NETRC_FILE"`
+	OtelEndpoint            string `envconfig:"PLUGIN_OTEL_ENDPOINT"`
+	OtelHeaders             string `envconfig:"PLUGIN_OTEL_HEADERS"`
+	PushgatewayURL          string `envconfig:"PLUGIN_PUSHGATEWAY_URL"`
+	Mode                    string `envconfig:"PLUGIN_MODE"`
+	Dockerfile              string `envconfig:"PLUGIN_DOCKERFILE"`
+	LayerDependencies       bool   `envconfig:"PLUGIN_LAYER_DEPENDENCIES"`
+	LabelProperties         string `envconfig:"PLUGIN_LABEL_PROPERTIES"`
+	ImageStats              bool   `envconfig:"PLUGIN_IMAGE_STATS"`
+	AnnotationProperties    bool   `envconfig:"PLUGIN_ANNOTATION_PROPERTIES"`
+	OCIArtifact             bool   `envconfig:"PLUGIN_OCI_ARTIFACT"`
+	NotationSignatures      bool   `envconfig:"PLUGIN_NOTATION_SIGNATURES"`
+	CosignArtifacts         bool   `envconfig:"PLUGIN_COSIGN_ARTIFACTS"`
+	TestReportURLs          string `envconfig:"PLUGIN_TEST_REPORT_URLS"`
+	TestsPassed             string `envconfig:"PLUGIN_TESTS_PASSED"`
+	TestsFailed             string `envconfig:"PLUGIN_TESTS_FAILED"`
+	TestsSkipped            string `envconfig:"PLUGIN_TESTS_SKIPPED"`
+	EnvFile                 string `envconfig:"PLUGIN_ENV_FILE"`
+	ImageListFile           string `envconfig:"PLUGIN_IMAGE_LIST_FILE"`
+	TagPattern              string `envconfig:"PLUGIN_TAG_PATTERN"`
+	ImageSettings           string `envconfig:"PLUGIN_IMAGE_SETTINGS"`
+	SkipPublish             bool   `envconfig:"PLUGIN_SKIP_PUBLISH"`
+	ParentBuildName         string `envconfig:"PLUGIN_PARENT_BUILD_NAME"`
+	ParentBuildNumber       string `envconfig:"PLUGIN_PARENT_BUILD_NUMBER"`
+	CheckLocalImage         bool   `envconfig:"PLUGIN_CHECK_LOCAL_IMAGE"`
+	BuildInfoPatch          string `envconfig:"PLUGIN_BUILDINFO_PATCH"`
+	ArtifactsInclude        string `envconfig:"PLUGIN_MODULE_ARTIFACTS_INCLUDE"`
+	ArtifactsExclude        string `envconfig:"PLUGIN_MODULE_ARTIFACTS_EXCLUDE"`
+	Mock                    bool   `envconfig:"PLUGIN_MOCK"`
+	ErrorFile               string `envconfig:"PLUGIN_ERROR_FILE"`
+	PublishRetries          int    `envconfig:"PLUGIN_PUBLISH_RETRIES" default:"3"`
+	RetryDelay              int    `envconfig:"PLUGIN_RETRY_DELAY" default:"2"`
+	HTTPTimeout             int    `envconfig:"PLUGIN_HTTP_TIMEOUT" default:"60"`
+	DialTimeout             int    `envconfig:"PLUGIN_DIAL_TIMEOUT" default:"30"`
+	TLSTimeout              int    `envconfig:"PLUGIN_TLS_TIMEOUT" default:"10"`
+	HTTPHeaders             string `envconfig:"PLUGIN_HTTP_HEADERS"`
+	Headers                 string `envconfig:"PLUGIN_HEADERS"`
+	CLIHomeDir              string `envconfig:"PLUGIN_CLI_HOME_DIR"`
+	CLIReportUsage          bool   `envconfig:"PLUGIN_CLI_REPORT_USAGE"`
+	FIPS                    bool   `envconfig:"PLUGIN_FIPS"`
+	RedactEnv               bool   `envconfig:"PLUGIN_REDACT_ENV" default:"true"`
+	ArtifactBuildProperties string `envconfig:"PLUGIN_ARTIFACT_BUILD_PROPERTIES"`
+	Principal               string `envconfig:"PLUGIN_PRINCIPAL"`
+	BuildTrigger            string `envconfig:"DRONE_BUILD_TRIGGER"`
+	SetPrincipal            bool   `envconfig:"PLUGIN_SET_PRINCIPAL" default:"false"`
+	AgentName               string `envconfig:"PLUGIN_AGENT_NAME"`
+	AgentVersion            string `envconfig:"PLUGIN_AGENT_VERSION"`
+	BuildAgentName          string `envconfig:"PLUGIN_BUILD_AGENT_NAME"`
+	BuildAgentVersion       string `envconfig:"PLUGIN_BUILD_AGENT_VERSION"`
+	BuildStarted            string `envconfig:"PLUGIN_BUILD_STARTED"`
+	DroneBuildStarted       string `envconfig:"DRONE_BUILD_STARTED"`
+	VCSEntries              string `envconfig:"PLUGIN_VCS_ENTRIES"`
+	VCSURL                  string `envconfig:"PLUGIN_VCS_URL"`
+	VCSRevision             string `envconfig:"PLUGIN_VCS_REVISION"`
+	VCSBranch               string `envconfig:"PLUGIN_VCS_BRANCH"`
+	VCSMessage              string `envconfig:"PLUGIN_VCS_MESSAGE"`
+	IssuesConfig            string `envconfig:"PLUGIN_ISSUES_CONFIG"`
+	IssuesTrackerName       string `envconfig:"PLUGIN_ISSUES_TRACKER_NAME"`
+	IssuesTrackerURL        string `envconfig:"PLUGIN_ISSUES_TRACKER_URL"`
+	IssuesRegexp            string `envconfig:"PLUGIN_ISSUES_REGEXP"`
+	IssuesKeyGroupIndex     int    `envconfig:"PLUGIN_ISSUES_KEY_GROUP_INDEX" default:"1"`
+	IssuesSummaryGroupIndex int    `envconfig:"PLUGIN_ISSUES_SUMMARY_GROUP_INDEX" default:"2"`
+	IssuesAggregate         bool   `envconfig:"PLUGIN_ISSUES_AGGREGATE"`
+	IssuesAggregationStatus string `envconfig:"PLUGIN_ISSUES_AGGREGATION_STATUS"`
+	PipelineProperties      bool   `envconfig:"PLUGIN_PIPELINE_PROPERTIES" default:"false"`
+	CommitProperties        bool   `envconfig:"PLUGIN_COMMIT_PROPERTIES" default:"false"`
+	PermissionCheck         bool   `envconfig:"PLUGIN_PERMISSION_CHECK" default:"true"`
+	EffectivePermissions    bool   `envconfig:"PLUGIN_EFFECTIVE_PERMISSION_CHECK" default:"false"`
+	BuildInfoRepo           string `envconfig:"PLUGIN_BUILD_INFO_REPO"`
+	APICompat               string `envconfig:"PLUGIN_API_COMPAT"`
+	TargetRepo              string `envconfig:"PLUGIN_TARGET_REPO"`
+	TargetAction            string `envconfig:"PLUGIN_TARGET_ACTION"`
+	PromoteTargetRepo       string `envconfig:"PLUGIN_PROMOTE_TARGET_REPO"`
+	PromoteTargetImage      string `envconfig:"PLUGIN_PROMOTE_TARGET_IMAGE"`
+	PromoteTargetTag        string `envconfig:"PLUGIN_PROMOTE_TARGET_TAG"`
+	PromoteCopy             bool   `envconfig:"PLUGIN_PROMOTE_COPY" default:"true"`
+	HelmChartName           string `envconfig:"PLUGIN_HELM_CHART_NAME"`
+	HelmChartVersion        string `envconfig:"PLUGIN_HELM_CHART_VERSION"`
+	HelmRepo                string `envconfig:"PLUGIN_HELM_REPO"`
+	HelmChartPath           string `envconfig:"PLUGIN_HELM_CHART_PATH"`
+	Artifacts               string `envconfig:"PLUGIN_ARTIFACTS"`
+	UploadSpec              string `envconfig:"PLUGIN_UPLOAD_SPEC"`
+	ArtifactsModule         string `envconfig:"PLUGIN_ARTIFACTS_MODULE"`
+	ManifestFile            string `envconfig:"PLUGIN_MANIFEST_FILE"`
+	Verify                  string `envconfig:"PLUGIN_VERIFY"`
+	CleanupOnFailure        string `envconfig:"PLUGIN_CLEANUP_ON_FAILURE"`
+	SlackWebhook            string `envconfig:"PLUGIN_SLACK_WEBHOOK"`
+	TeamsWebhook            string `envconfig:"PLUGIN_TEAMS_WEBHOOK"`
+	SummaryFile             string `envconfig:"PLUGIN_SUMMARY_FILE"`
+	RequireGit              bool   `envconfig:"PLUGIN_REQUIRE_GIT"`
+	Strict                  bool   `envconfig:"PLUGIN_STRICT"`
+	PrincipalFailure        string `envconfig:"PLUGIN_PRINCIPAL_FAILURE"`
+	Concurrency             int    `envconfig:"PLUGIN_CONCURRENCY" default:"4"`
+	SearchMode              string `envconfig:"PLUGIN_SEARCH_MODE" default:"path"`
+	SearchAPI               string `envconfig:"PLUGIN_SEARCH_API" default:"cli"`
+	ManifestPathTemplate    string `envconfig:"PLUGIN_MANIFEST_PATH_TEMPLATE" default:"{name}/{tag}"`
+	ManifestNames           string `envconfig:"PLUGIN_MANIFEST_NAMES" default:"manifest.json,list.manifest.json"`
+	RepoKey                 string `envconfig:"PLUGIN_REPO_KEY"`
+	RegistryMap             string `envconfig:"PLUGIN_REGISTRY_MAP"`
 }
 
-// Artifact represents a Docker image artifact with its SHA256 hash.
+// Artifact represents a Docker image artifact with its path and SHA256 hash.
 type Artifact struct {
+	Path   string `json:"path"`
 	Sha256 string `json:"sha256"`
 }
 
@@ -50,174 +167,1001 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && (os.Args[1] == "--version" || os.Args[1] == "version") {
+		fmt.Println(versionString())
+		return
+	}
+	logrus.Info(versionString())
+
+	// Read the settings versioned in the repository, if any, as PLUGIN_* variables
+	if settingsFile := os.Getenv("PLUGIN_SETTINGS_FILE"); settingsFile != "" {
+		if err := loadSettingsFile(settingsFile); err != nil {
+			exitWithError(fmt.Errorf("%w: error loading settings file: %w", errInvalidSettings, err), Args{})
+		}
+	}
+
 	var args Args
 	// Process environment variables into the Args struct
 	err := envconfig.Process("", &args)
 	if err != nil {
-		logrus.Fatalln("Error processing environment variables:", err)
+		exitWithError(fmt.Errorf("%w: error processing environment variables: %w", errInvalidSettings, err), Args{})
 	}
 
 	// Execute the main functionality of the program
 	if err := Exec(context.Background(), args); err != nil {
-		logrus.Fatalln("Error:", err)
+		exitWithError(err, args)
+	}
+}
+
+// exitWithError summarizes the failure in PLUGIN_ERROR_FILE for the CI system
+// to show, then logs it and exits. The error file is read from the environment
+// when the settings could not be processed.
+func exitWithError(err error, args Args) {
+	errorFile := firstNonEmpty(args.ErrorFile, os.Getenv("PLUGIN_ERROR_FILE"))
+	if errorFile != "" {
+		if fileErr := writeErrorFile(errorFile, newErrorReport(err, args)); fileErr != nil {
+			logrus.Warnf("error writing error file: %v", fileErr)
+		}
 	}
+	if hint := errorHint(err, args); hint != "" {
+		logrus.Fatalf("Error: %v\nHint: %s", err, hint)
+	}
+	logrus.Fatalln("Error:", err)
 }
 
 // Exec contains the main logic for executing commands related to Docker images and JFrog.
-func Exec(ctx context.Context, args Args) error {
+func Exec(ctx context.Context, args Args) (err error) {
+
+	// Refuse to connect anywhere with non-FIPS crypto when FIPS mode is required
+	if err := checkFIPS(args); err != nil {
+		return err
+	}
+
+	// Turn the warnings about incomplete build info into errors
+	applyStrictMode(&args)
+
+	// DRONE_REPO_BRANCH is the default branch of the repository, the built
+	// branch is DRONE_COMMIT_BRANCH
+	args.BranchName = firstNonEmpty(args.CommitBranch, args.BranchName)
+
+	// Outside of Drone, read the VCS details from the CI system's own variables
+	applyCIFallbacks(&args)
 
 	// If GitPath is null, assign default value
 	if args.GitPath == "" {
 		args.GitPath = args.DefaultPath
 	}
 
-	// Parse the Docker image to extract repository, image name, and tag
-	repo, imageName, imageTag, err := parseDockerImage(args.DockerImage)
-	if err != nil {
-		logrus.Fatalln("error parsing Docker image:", err)
+	// Name and number the build after the repository and pipeline run unless set
+	args.BuildName = firstNonEmpty(args.BuildName, args.RepoName)
+	args.BuildNumber = firstNonEmpty(args.BuildNumber, args.DroneBuildNumber)
+
+	// Link the build info to the pipeline execution unless a build URL is set
+	args.BuildURL = firstNonEmpty(args.BuildURL, args.BuildLink)
+
+	// PLUGIN_HEADERS is the former name of PLUGIN_HTTP_HEADERS
+	args.HTTPHeaders = firstNonEmpty(args.HTTPHeaders, args.Headers)
+
+	// Explicit VCS settings take precedence over the DRONE_* variables
+	vcsOverride := vcsEntry{URL: args.VCSURL, Revision: args.VCSRevision, Branch: args.VCSBranch, Message: args.VCSMessage}
+	if vcsOverride != (vcsEntry{}) {
+		args.RepoURL = firstNonEmpty(vcsOverride.URL, args.RepoURL)
+		args.CommitSha = firstNonEmpty(vcsOverride.Revision, args.CommitSha)
+		args.BranchName = firstNonEmpty(vcsOverride.Branch, args.BranchName)
+		args.CommitMessage = firstNonEmpty(vcsOverride.Message, args.CommitMessage)
+	}
+
+	// Publish to an in-process fake Artifactory to test the pipeline wiring
+	if args.Mock {
+		server := startMockArtifactory()
+		defer server.Close()
+		args.URL = server.URL + "/artifactory/"
+		if args.Username == "" && args.APIKey == "" && args.AccessToken == "" {
+			args.AccessToken = "mock"
+		}
+		logrus.Warnf("Mock mode, publishing to a fake Artifactory at %s", args.URL)
+	}
+
+	// Read any credentials not set explicitly from a mounted secret directory
+	if err := loadCredentialsDir(&args); err != nil {
+		return err
+	}
+
+	// Fall back to a .netrc entry for the Artifactory host
+	if err := loadNetrc(&args); err != nil {
+		return err
+	}
+
+	// Add the images an earlier step listed in a file, e.g. for matrix builds
+	if args.ImageListFile != "" {
+		listed, err := readImageListFile(args.ImageListFile)
+		if err != nil {
+			return err
+		}
+		args.DockerImage = strings.Trim(args.DockerImage+","+listed, ", ")
 	}
 
+	// Report every problem with the settings before doing any work
+	if err := validateArgs(args); err != nil {
+		return fmt.Errorf("%w:\n%w", errInvalidSettings, err)
+	}
+	httpClient.Timeout = time.Duration(args.HTTPTimeout) * time.Second
+	httpClient.Transport = newTransport(time.Duration(args.DialTimeout)*time.Second, time.Duration(args.TLSTimeout)*time.Second)
+
 	// Sanitize the URL for JFrog
 	sanitizedURL, err := sanitizeURL(args.URL)
 	if err != nil {
 		return err
 	}
 
-	// Create a query to find the manifest.json file in JFrog
+	// Keep the jfrog CLI state of this run apart from other steps on the runner
+	cleanupCLIHome, err := prepareCLIHome(args)
+	if err != nil {
+		return err
+	}
+	defer cleanupCLIHome()
+	if err := configureCLIUsageReport(args); err != nil {
+		return err
+	}
+
+	// Only check connectivity when running as a healthcheck
+	if args.Mode == "healthcheck" {
+		return Healthcheck(ctx, args, sanitizedURL)
+	}
+
+	if err := checkBuildIdentifiers(&args); err != nil {
+		return err
+	}
+
+	// Target the REST API of the server version
+	resolveAPICompat(ctx, &args, sanitizedURL)
+	if err := checkAPICompat(args); err != nil {
+		return err
+	}
+
+	// Only publish the build collected by earlier steps
+	if args.Mode == "publish" {
+		return PublishCollected(ctx, args, sanitizedURL)
+	}
+
+	// Parse the Docker images to extract repository, image name, and tag
+	images, err := parseDockerImages(args.DockerImage, args)
+	if err != nil {
+		return err
+	}
+
+	// Record every tag of the images matching the pattern instead of the given tag
+	if args.TagPattern != "" {
+		images, err = expandTagPattern(ctx, args, sanitizedURL, images, args.TagPattern)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record each phase so it can be summarized and exported once the run is over
+	t := newTracer("publish-build-info")
+	defer func() {
+		logrus.Infof("Timing summary:\n%s", t.summary())
+		if exportErr := t.export(ctx, args, err); exportErr != nil {
+			logrus.Warnf("error exporting trace: %v", exportErr)
+		}
+		if pushErr := pushMetrics(ctx, args, images[0].Repo, t, err); pushErr != nil {
+			logrus.Warnf("error pushing metrics: %v", pushErr)
+		}
+	}()
+
+	// Summarize the result in markdown for the pipeline UI
+	result := publishSummary{Images: images, BuildName: args.BuildName, Number: args.BuildNumber}
+	if file := summaryFile(args); file != "" {
+		collector := &warningCollector{}
+		logrus.AddHook(collector)
+		defer func() {
+			if summaryErr := writeSummary(file, result, collector.warnings(), err); summaryErr != nil {
+				if strictErr := warnOrFail(args, "error writing step summary: %v", summaryErr); err == nil {
+					err = strictErr
+				}
+			}
+		}()
+	}
+
+	// Leave nothing behind for a rerun to merge with when the run fails
+	published := false
+	if args.CleanupOnFailure != "" {
+		defer func() {
+			if err == nil {
+				return
+			}
+			if cleanupErr := cleanupBuild(ctx, args, sanitizedURL, published); cleanupErr != nil {
+				logrus.Warnf("error cleaning up: %v", cleanupErr)
+			}
+		}()
+	}
+
+	var repos []string
+	for _, img := range images {
+		if !slices.Contains(repos, img.Repo) {
+			repos = append(repos, img.Repo)
+		}
+	}
+
+	// Fail early if the credentials lack the permissions the run needs
+	if args.PermissionCheck {
+		err = t.phase("permissions", func() error {
+			return checkPermissions(ctx, args, sanitizedURL, repos)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Make sure the images are searched in Docker repositories
+	err = t.phase("repositories", func() error {
+		remotes := map[string]repositoryConfig{}
+		for _, repo := range repos {
+			config, err := checkImageRepository(ctx, args, sanitizedURL, repo)
+			if err != nil {
+				return err
+			}
+			if config.RClass == "remote" {
+				remotes[repo] = config
+			}
+		}
+		// Images pulled or pushed through a remote repository are stored in its cache
+		for i := range images {
+			if config, ok := remotes[images[i].Repo]; ok {
+				useRemoteCache(&images[i], config)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Find the manifest of each image in JFrog
+	err = t.phase("search", func() error {
+		return forEachImage(images, args.Concurrency, func(img *dockerImage) error {
+			manifestArtifact, err := searchManifest(ctx, args, sanitizedURL, img.Repo, img.Name, img.Tag, img.RefDigest)
+			if errors.Is(err, errManifestNotFound) && args.SearchMode != "digest" {
+				return tagNotFoundError(ctx, args, sanitizedURL, *img)
+			}
+			img.Manifest = manifestArtifact
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// Compare the digests with the images pushed from the local Docker daemon
+	if args.CheckLocalImage {
+		if dockerDaemonAvailable() {
+			err = t.phase("local-image", func() error {
+				for _, img := range images {
+					if err := checkLocalImage(img); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		} else {
+			logrus.Warn("Skipping the local image check, no Docker daemon is available")
+		}
+	}
+
+	// Create the Docker build of each image in JFrog. OCI artifacts are recorded
+	// from their manifest instead, as the jfrog CLI only handles images.
+	err = t.phase("build-create", func() error {
+		if args.OCIArtifact {
+			return nil
+		}
+		return forEachImage(images, args.Concurrency, func(img *dockerImage) error {
+			return createDockerBuild(ctx, args, sanitizedURL, img.Repo, img.Name, img.Tag, img.Manifest.Sha256)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// If Git information is available, add it to the build info
+	err = t.phase("git-add", func() error {
+		return addGitInfo(args, sanitizedURL)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Upload the files of the upload spec as artifacts of the build
+	if args.UploadSpec != "" {
+		err = t.phase("upload", func() error {
+			return uploadArtifacts(args, sanitizedURL)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Collect changes to make to the build info before it is published
+	var edits []buildInfoEdit
+
+	// Record the files already in Artifactory that belong to the build
+	if args.Artifacts != "" {
+		err = t.phase("artifacts", func() error {
+			artifacts, err := searchArtifacts(args, sanitizedURL)
+			if err != nil {
+				return err
+			}
+			if len(artifacts) > 0 {
+				edits = append(edits, addModuleArtifacts(artifactsModuleID(args), artifacts))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Replace what build-add-git read from the repository with the explicit VCS settings
+	if vcsOverride != (vcsEntry{}) {
+		edits = append(edits, overrideVCS(vcsOverride))
+	}
+
+	// Record the additional repositories the image was assembled from
+	if args.VCSEntries != "" {
+		entries, err := parseVCSEntries(args.VCSEntries)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, addVCSEntries(entries))
+	}
+
+	// Download the manifests when a feature needs their contents
+	if args.LayerDependencies || args.LabelProperties != "" || args.ImageStats || args.AnnotationProperties || args.OCIArtifact || args.ManifestFile != "" || os.Getenv("DRONE_OUTPUT") != "" {
+		err = t.phase("manifest", func() error {
+			return forEachImage(images, args.Concurrency, func(img *dockerImage) error {
+				manifest, err := fetchManifest(ctx, args, sanitizedURL, img.Manifest.Path)
+				img.manifest = manifest
+				return err
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the OCI artifacts in modules of their own
+	if args.OCIArtifact {
+		err = t.phase("oci-artifacts", func() error {
+			for _, img := range images {
+				module, err := ociArtifactModule(ctx, args, sanitizedURL, img)
+				if err != nil {
+					return err
+				}
+				edits = append(edits, addModule(module))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Apply the module ID and properties set for each image
+	for _, img := range images {
+		if img.ModuleIDOverride != "" && !args.OCIArtifact {
+			edits = append(edits, renameModule(img.cliModuleID(), img.ModuleIDOverride))
+		}
+		if len(img.Properties) > 0 {
+			edits = append(edits, addModuleProperties(img.moduleID(), img.Properties))
+		}
+	}
+
+	// With several images, per-image build properties go to the image's module
+	imageProperties := func(img dockerImage, properties map[string]string) buildInfoEdit {
+		if len(images) > 1 {
+			return addModuleProperties(img.moduleID(), properties)
+		}
+		return addBuildProperties(properties)
+	}
+
+	for i, img := range images {
+		manifest := img.manifest
+		if manifest == nil {
+			break
+		}
+
+		// Share the manifest with later steps, numbering the outputs of all but the first image
+		suffix := ""
+		if i > 0 {
+			suffix = "_" + strconv.Itoa(i+1)
+		}
+		outputs := map[string]string{
+			"MANIFEST_PATH" + suffix:       img.Manifest.Path,
+			"MANIFEST_SHA256" + suffix:     img.Manifest.Sha256,
+			"MANIFEST_MEDIA_TYPE" + suffix: manifest.MediaType,
+			"CONFIG_DIGEST" + suffix:       manifest.Config.Digest,
+		}
+		if args.ManifestFile != "" {
+			manifestFile, err := saveManifest(args, manifest, i)
+			if err != nil {
+				return err
+			}
+			outputs["MANIFEST_FILE"+suffix] = manifestFile
+		}
+		if err := writeOutputs(outputs); err != nil {
+			return err
+		}
+
+		// Record the image layers as dependencies of the docker module
+		if args.LayerDependencies {
+			if len(manifest.Layers) == 0 {
+				if err := warnOrFail(args, "Manifest %s has no layers to record as dependencies", img.Manifest.Path); err != nil {
+					return err
+				}
+			} else {
+				edits = append(edits, addModuleDependencies(img.moduleID(), layerDependencies(manifest)))
+			}
+		}
+
+		// Record the compressed size and layer count of the image
+		if args.ImageStats {
+			if len(manifest.Layers) == 0 {
+				if err := warnOrFail(args, "Manifest %s has no layers to compute the image size from", img.Manifest.Path); err != nil {
+					return err
+				}
+			} else {
+				edits = append(edits, imageProperties(img, imageStats(manifest)))
+			}
+		}
+
+		// Record the annotations of the manifest or index, such as release metadata
+		if args.AnnotationProperties && len(manifest.Annotations) > 0 {
+			edits = append(edits, imageProperties(img, manifest.Annotations))
+		}
+	}
+
+	// Record the notation signatures that refer to the images
+	if args.NotationSignatures {
+		err = t.phase("signatures", func() error {
+			for _, img := range images {
+				referrers, err := fetchReferrers(ctx, args, sanitizedURL, img)
+				if err != nil {
+					if err := warnOrFail(args, "Skipping the signatures of %s: %v", img.Ref, err); err != nil {
+						return err
+					}
+					continue
+				}
+				if properties := notationSignatureProperties(img, referrers); properties != nil {
+					edits = append(edits, imageProperties(img, properties))
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the cosign signatures and attestations as artifacts of the images,
+	// so promoting the build carries them along
+	if args.CosignArtifacts {
+		err = t.phase("cosign", func() error {
+			for _, img := range images {
+				artifacts, err := cosignArtifacts(ctx, args, sanitizedURL, img)
+				if err != nil {
+					return fmt.Errorf("error reading the cosign tags of %s: %w", img.Ref, err)
+				}
+				if len(artifacts) > 0 {
+					edits = append(edits, addModuleArtifacts(img.moduleID(), artifacts))
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Copy the image labels into build info and/or artifact properties
+	if args.LabelProperties != "" {
+		err = t.phase("labels", func() error {
+			for _, img := range images {
+				labels, err := fetchImageLabels(ctx, args, sanitizedURL, img.Manifest.Path, img.manifest)
+				if err != nil {
+					return err
+				}
+				if len(labels) == 0 {
+					continue
+				}
+				if args.LabelProperties == "build" || args.LabelProperties == "both" {
+					edits = append(edits, imageProperties(img, labels))
+				}
+				if args.LabelProperties == "artifact" || args.LabelProperties == "both" {
+					if err := setArtifactProperties(args, sanitizedURL, img.Manifest.Path, labels); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the base images of the Dockerfile as dependencies of the first
+	// image's docker module, with the Dockerfile's path and hash
+	if args.Dockerfile != "" {
+		err = t.phase("dependencies", func() error {
+			properties, err := dockerfileProperties(args)
+			if err != nil {
+				return err
+			}
+			edits = append(edits, imageProperties(images[0], properties))
+
+			dependencies, err := resolveBaseImageDependencies(ctx, args, sanitizedURL)
+			if err != nil {
+				return err
+			}
+			if len(dependencies) > 0 {
+				edits = append(edits, addModuleDependencies(images[0].moduleID(), dependencies))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Publish the Helm chart of the service in the same build
+	if args.HelmChartName != "" {
+		err = t.phase("helm", func() error {
+			module, err := helmChartModule(ctx, args, sanitizedURL)
+			if err != nil {
+				return err
+			}
+			edits = append(edits, addModule(module))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Record the pipeline execution the build came from
+	if args.PipelineProperties {
+		if properties := pipelineProperties(); len(properties) > 0 {
+			edits = append(edits, addBuildProperties(properties))
+		}
+	}
+
+	// Record the build parameters staged in the env file as the build environment
+	if args.EnvFile != "" {
+		properties, err := envFileProperties(args.EnvFile)
+		if err != nil {
+			return err
+		}
+		if args.RedactEnv {
+			redactSecrets(properties)
+		}
+		edits = append(edits, addBuildProperties(properties))
+	}
+
+	// Record the commit message, author and link, and the pull request the build is for
+	if args.CommitProperties {
+		if properties := commitProperties(args); len(properties) > 0 {
+			edits = append(edits, addBuildProperties(properties))
+		}
+		if properties := pullRequestProperties(args); properties != nil {
+			edits = append(edits, addBuildProperties(properties))
+		}
+	}
+
+	// Link the build to its test evidence
+	if properties := testReportProperties(args); len(properties) > 0 {
+		edits = append(edits, addBuildProperties(properties))
+	}
+
+	// Reference the aggregating build this build is part of
+	if args.ParentBuildName != "" {
+		edits = append(edits,
+			setBuildInfoField("parentName", args.ParentBuildName),
+			setBuildInfoField("parentNumber", args.ParentBuildNumber),
+		)
+	}
+
+	// Record the principal set explicitly, or with PLUGIN_SET_PRINCIPAL who
+	// triggered the build. Either takes the REST publish path, so the principal
+	// chosen by the jfrog CLI is kept by default
+	principal := args.Principal
+	setPrincipal := args.SetPrincipal || principal != ""
+	if principal == "" {
+		principal = args.BuildTrigger
+	}
+	if principal != "" && setPrincipal {
+		edits = append(edits, setBuildInfoField("principal", principal))
+	}
+
+	// Override the agent details the jfrog CLI records
+	if args.AgentName != "" || args.AgentVersion != "" {
+		edits = append(edits, setAgent("agent", args.AgentName, args.AgentVersion))
+	}
+	if args.BuildAgentName != "" || args.BuildAgentVersion != "" {
+		edits = append(edits, setAgent("buildAgent", args.BuildAgentName, args.BuildAgentVersion))
+	}
+
+	// Use the pipeline start time rather than the time the plugin ran
+	buildStarted := args.BuildStarted
+	if buildStarted == "" {
+		buildStarted = args.DroneBuildStarted
+	}
+	if buildStarted != "" {
+		started, err := parseBuildStarted(buildStarted)
+		if err != nil {
+			return err
+		}
+		edits = append(edits,
+			setBuildInfoField("started", started.Format(buildInfoTimeFormat)),
+			setBuildInfoField("durationMillis", time.Since(started).Milliseconds()),
+		)
+	}
+
+	// Leave out the artifacts of the docker modules the patterns filter out
+	if args.ArtifactsInclude != "" || args.ArtifactsExclude != "" {
+		include, exclude := splitPatterns(args.ArtifactsInclude), splitPatterns(args.ArtifactsExclude)
+		for _, img := range images {
+			edits = append(edits, filterModuleArtifacts(img.moduleID(), include, exclude))
+		}
+	}
+
+	// Apply the patch last, so it can change anything set above
+	if args.BuildInfoPatch != "" {
+		patch, err := parseBuildInfoPatch(args.BuildInfoPatch)
+		if err != nil {
+			return err
+		}
+		edits = append(edits, applyMergePatch(patch))
+	}
+
+	// Leave the build to a later step running in publish mode, which applies the edits
+	if args.SkipPublish {
+		if len(edits) > 0 {
+			err = t.phase("save-edits", func() error {
+				return saveBuildInfoEdits(args, sanitizedURL, edits)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		logrus.Infof("Skipping publish, the build is published by a step with PLUGIN_MODE publish")
+		return nil
+	}
+
+	// Publish the build information to JFrog
+	err = t.phase("publish", func() error {
+		return publishBuildInfo(ctx, args, sanitizedURL, edits)
+	})
+	if err != nil {
+		return err
+	}
+	published = true
+
+	// Check that nothing was dropped from the stored build info
+	if args.Verify != "" || args.PrincipalFailure != "" && principal != "" && setPrincipal {
+		expected := expectedBuildInfo{VCS: vcsEntry{URL: vcsOverride.URL}}
+		for _, img := range images {
+			expected.Modules = append(expected.Modules, img.moduleID())
+		}
+		if args.RepoURL != "" && args.BranchName != "" && args.CommitSha != "" {
+			expected.VCS.Revision = args.CommitSha
+		}
+		if setPrincipal {
+			expected.Principal = principal
+		}
+		err = t.phase("verify", func() error {
+			return verifyBuildInfo(ctx, args, sanitizedURL, expected)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Link the image artifacts back to the published build
+	if args.ArtifactBuildProperties != "" {
+		err = t.phase("artifact-properties", func() error {
+			for _, img := range images {
+				target := img.Manifest.Path
+				if args.ArtifactBuildProperties == "all" {
+					target = path.Dir(img.Manifest.Path) + "/*"
+				}
+				err := setArtifactProperties(args, sanitizedURL, target, map[string]string{
+					"build.name":   args.BuildName,
+					"build.number": args.BuildNumber,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Add the build to the parent build, published by a later step
+	if args.ParentBuildName != "" {
+		err = t.phase("parent", func() error {
+			return appendToParentBuild(args, sanitizedURL)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Stage the image in the release repository
+	if args.TargetRepo != "" {
+		err = t.phase(firstNonEmpty(args.TargetAction, "copy"), func() error {
+			for _, img := range images {
+				if err := stageImage(ctx, args, sanitizedURL, path.Dir(img.Manifest.Path)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Promote the image itself, not only the build
+	if args.PromoteTargetRepo != "" {
+		err = t.phase("promote", func() error {
+			for _, img := range images {
+				if err := promoteImage(args, sanitizedURL, img.Repo, img.Name, img.Tag); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// Show where the published build is in the JFrog UI, and link the outputs,
+	// summary and notifications to it
+	link, linkErr := publishedBuildLink(ctx, args, sanitizedURL)
+	if linkErr != nil {
+		logrus.Warnf("error building the build link: %v", linkErr)
+	} else {
+		logrus.Infof("Build info published at %s", link)
+		if err := writeOutputs(map[string]string{"BUILD_INFO_LINK": link}); err != nil {
+			return err
+		}
+	}
+	result.Link = link
+
+	// Tell the team where to find the published build
+	if args.SlackWebhook != "" || args.TeamsWebhook != "" {
+		sendNotifications(ctx, args, result)
+	}
+	return nil
+}
+
+// searchManifest searches JFrog for the manifest of the image and returns its
+// path and SHA256 hash. In the digest search mode the manifest is found by its
+// digest anywhere in the repository rather than under <image>/<tag>.
+func searchManifest(ctx context.Context, args Args, sanitizedURL, repo, imageName, imageTag, digest string) (Artifact, error) {
+	// Create a query to find the manifest file in JFrog. Images pushed as an OCI
+	// index or multi-arch manifest list are stored as list.manifest.json instead.
+	names := manifestNameCriteria(args.ManifestNames)
+	searchPath := manifestSearchPath(args.ManifestPathTemplate, imageName, imageTag)
+	find := map[string]interface{}{
+		"repo": repo,
+		"path": pathCriterion(searchPath),
+		"$or":  names,
+	}
+	if args.SearchMode == "digest" {
+		// Artifactory records the digest as a property of the manifest, which is
+		// also the SHA256 of the manifest file
+		find = map[string]interface{}{
+			"repo": repo,
+			"$and": []map[string]interface{}{
+				{"$or": names},
+				{"$or": []map[string]interface{}{
+					{"@docker.manifest.digest": digest},
+					{"sha256": strings.TrimPrefix(digest, "sha256:")},
+				}},
+			},
+		}
+	}
+
+	// Search through the REST API directly when asked to
+	if args.SearchAPI == "rest" {
+		artifacts, err := searchAQL(ctx, args, sanitizedURL, find)
+		if err != nil {
+			return Artifact{}, err
+		}
+		return selectManifest(artifacts, args.ManifestNames, repo, searchPath, imageName, imageTag)
+	}
+
 	query := map[string]interface{}{
 		"files": []map[string]interface{}{
 			{
 				"aql": map[string]interface{}{
-					"items.find": map[string]interface{}{
-						"repo": repo,
-						"path": imageName + "/" + imageTag,
-						"name": "manifest.json",
-					},
+					"items.find": find,
 				},
 			},
 		},
 	}
 
-	// Create a JSON file to hold the query
-	queryFile, err := os.Create("query.json")
+	// Create a JSON file to hold the query, unique to the image as images are searched concurrently
+	queryFile, err := os.CreateTemp("", "query-*.json")
 	if err != nil {
-		logrus.Fatalln("error creating query.json file:", err)
+		return Artifact{}, fmt.Errorf("error creating query file: %w", err)
 	}
+	defer os.Remove(queryFile.Name())
 	defer queryFile.Close()
 
 	// Encode the query into the JSON file
 	encoder := json.NewEncoder(queryFile)
 	encoder.SetIndent("", "  ")
 	if err := encoder.Encode(query); err != nil {
-		logrus.Fatalln("failed to encode query to query.json:", err)
+		return Artifact{}, fmt.Errorf("failed to encode query to %s: %w", queryFile.Name(), err)
 	}
 
 	// Prepare the command to search for the manifest file in JFrog
-	cmdArgs := []string{"jfrog", "rt", "s", "--spec=query.json", "--url=" + sanitizedURL}
+	cmdArgs := []string{"jfrog", "rt", "s", "--spec=" + queryFile.Name(), "--url=" + sanitizedURL}
 	cmdArgs, err = setAuthParams(cmdArgs, args)
 	if err != nil {
 		logrus.Errorf("error setting auth parameters: %v", err)
 	}
 
-	// Run the command and capture the output
-	output, err := runCommandAndCaptureOutput(cmdArgs)
+	// Run the command and capture the results, which the CLI writes to stdout
+	// apart from its log and progress messages
+	output, err := runCommandAndCaptureStdout(cmdArgs)
 	if err != nil {
-		logrus.Fatalln("error executing jfrog rt s command: ", err)
+		return Artifact{}, fmt.Errorf("error executing jfrog rt s command: %w", err)
 	}
 
-	// Extract the SHA256 hash from the command output
-	sha256, err := extractSha256FromOutput(output)
+	// Extract the manifest from the search results
+	artifacts, err := parseSearchOutput(output)
 	if err != nil {
-		return err
+		return Artifact{}, err
 	}
+	return selectManifest(artifacts, args.ManifestNames, repo, searchPath, imageName, imageTag)
+}
 
+// createDockerBuild writes the image file for the resolved manifest and registers it as a Docker build in JFrog.
+func createDockerBuild(ctx context.Context, args Args, sanitizedURL, repo, imageName, imageTag, sha256 string) error {
 	// Prepare the content for the image file
 	imageFileContent := fmt.Sprintf("%s/%s:%s@sha256:%s", repo, imageName, imageTag, sha256)
-	imageFileName := "image_info.txt"
 
-	// Create a file to store the image information
-	imageFile, err := os.Create(imageFileName)
+	// Create a file to store the image information, unique to the image as builds are created concurrently
+	imageFile, err := os.CreateTemp("", "image-info-*.txt")
 	if err != nil {
-		logrus.Errorf("error creating image file: %v", err)
+		return fmt.Errorf("error creating image file: %w", err)
 	}
+	imageFileName := imageFile.Name()
+	defer os.Remove(imageFileName)
 	defer imageFile.Close()
 
 	// Write the image information to the file
 	if _, err := imageFile.WriteString(imageFileContent); err != nil {
 		logrus.Errorf("error writing to image file: %v", err)
+		if args.Strict {
+			return fmt.Errorf("error writing to image file: %w", err)
+		}
 	}
 
 	// Command to create the Docker build in JFrog
-	logrus.Infof("Setting Build Properties to %s", args.DockerImage)
-	cmdArgs = []string{"jfrog", "rt", "build-docker-create", repo, "--build-name=" + args.BuildName, "--build-number=" + args.BuildNumber, "--image-file=" + imageFileName, "--url=" + sanitizedURL}
+	logrus.Infof("Setting Build Properties to %s/%s:%s", repo, imageName, imageTag)
+	cmdArgs := []string{"jfrog", "rt", "build-docker-create", repo, "--build-name=" + args.BuildName, "--build-number=" + args.BuildNumber, "--image-file=" + imageFileName, "--url=" + sanitizedURL}
+	cmdArgs = appendProjectFlag(cmdArgs, args)
 	cmdArgs, err = setAuthParams(cmdArgs, args)
 	if err != nil {
 		logrus.Errorf("error setting auth parameters: %v", err)
 	}
 
 	// Execute the build creation command
-	if err := runCommand(cmdArgs); err != nil {
-		logrus.Fatalln("error executing jfrog rt build-docker-create command:", err)
+	if err := runCommandWithRetries(ctx, args, cmdArgs); err != nil {
+		return fmt.Errorf("error executing jfrog rt build-docker-create command: %w", err)
 	}
+	return nil
+}
 
-	// If Git information is available, add it to the build info
+// addGitInfo adds the VCS details of the Git repository to the build info when Git information is available,
+// along with the issues referenced by the commits since the previous build when issue collection is configured.
+func addGitInfo(args Args, sanitizedURL string) error {
 	logrus.Info("Setting Git Properties")
 	if args.RepoURL != "" && args.BranchName != "" && args.CommitSha != "" {
-		cmdArgs = []string{"jfrog", "rt", "build-add-git", args.BuildName, args.BuildNumber, args.GitPath}
+		cmdArgs := appendProjectFlag([]string{"jfrog", "rt", "build-add-git", args.BuildName, args.BuildNumber, args.GitPath}, args)
+		if issuesConfigEnabled(args) {
+			configPath, err := prepareIssuesConfig(args, sanitizedURL)
+			if err != nil {
+				return err
+			}
+			cmdArgs = append(cmdArgs, "--config="+configPath, "--server-id="+issuesServerID)
+		}
 		if err := runCommand(cmdArgs); err != nil {
-			logrus.Fatalln("error executing jfrog rt build-add-git command:", err)
+			return fmt.Errorf("error executing jfrog rt build-add-git command: %w", err)
 		}
+		return nil
 	}
 
-	// Command to publish the build information to JFrog
-	logrus.Info("Publishing Build Info")
-	cmdArgs = []string{"jfrog", "rt", "build-publish", "--build-url=" + args.BuildURL, "--url=" + sanitizedURL, args.BuildName, args.BuildNumber}
-	cmdArgs, err = setAuthParams(cmdArgs, args)
-	if err != nil {
-		logrus.Errorf("error setting auth parameters: %v", err)
+	var missing []string
+	for _, v := range []struct{ name, value string }{
+		{"DRONE_GIT_HTTP_URL", args.RepoURL},
+		{"DRONE_COMMIT_BRANCH", args.BranchName},
+		{"DRONE_COMMIT_SHA", args.CommitSha},
+	} {
+		if v.value == "" {
+			missing = append(missing, v.name)
+		}
 	}
-
-	// Execute the build publish command
-	if err := runCommand(cmdArgs); err != nil {
-		logrus.Fatalln("error executing jfrog rt build-publish command:", err)
+	if args.RequireGit {
+		return fmt.Errorf("git information is required but missing %s", strings.Join(missing, ", "))
 	}
-
+	logrus.Warnf("Skipping Git information, missing %s", strings.Join(missing, ", "))
 	return nil
 }
 
-// extractSha256FromOutput extracts the SHA256 hash from the command output.
-func extractSha256FromOutput(output string) (string, error) {
-	// Split the output into lines
-	lines := strings.Split(output, "\n")
-
-	// Find the line where the JSON array starts
-	var jsonStr string
-	startIndex := -1
-	for i, line := range lines {
-		if strings.HasPrefix(line, "[") {
-			startIndex = i
-			break
-		}
+// parseSearchOutput parses the search results jfrog rt s writes to stdout.
+func parseSearchOutput(output []byte) ([]Artifact, error) {
+	var artifacts []Artifact
+	if err := json.Unmarshal(output, &artifacts); err != nil {
+		return nil, fmt.Errorf("error parsing jfrog rt s output: %w", err)
 	}
+	return artifacts, nil
+}
 
-	if startIndex != -1 {
-		jsonStr = strings.Join(lines[startIndex:], "\n")
-	} else {
-		logrus.Errorf("could not find JSON output in the command response")
+// selectManifest returns the manifest to record among the search results. Only
+// manifests in the image repository are considered. When several match, as
+// for an image retagged within the repository, the one under the searched
+// path, <image>/<tag> by default, is preferred, then the one whose file name
+// comes first in the PLUGIN_MANIFEST_NAMES list, by default an image manifest
+// over a manifest list as build-docker-create does, then the first path in
+// order, so the same manifest is picked on every run.
+func selectManifest(artifacts []Artifact, manifestNames, repo, searchPath, imageName, imageTag string) (Artifact, error) {
+	var candidates []Artifact
+	for _, artifact := range artifacts {
+		if strings.HasPrefix(artifact.Path, repo+"/") {
+			candidates = append(candidates, artifact)
+		}
 	}
-
-	// Parse the JSON output
-	var artifacts []Artifact
-	err := json.Unmarshal([]byte(jsonStr), &artifacts)
-	if err != nil {
-		logrus.Errorf("error parsing JSON: %v", err)
+	if len(candidates) == 0 {
+		return Artifact{}, errManifestNotFound
 	}
 
-	if len(artifacts) == 0 {
-		logrus.Errorf("no results found in jfrog output")
+	expectedDir := repo + "/" + searchPath
+	names := splitPatterns(manifestNames)
+	rank := func(artifact Artifact) int {
+		r := len(names)
+		for i, name := range names {
+			if matched, _ := path.Match(name, path.Base(artifact.Path)); matched {
+				r = i
+				break
+			}
+		}
+		if matched, _ := path.Match(expectedDir, path.Dir(artifact.Path)); !matched {
+			r += len(names) + 1
+		}
+		return r
 	}
-
-	return artifacts[0].Sha256, nil
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if rank(candidates[i]) != rank(candidates[j]) {
+			return rank(candidates[i]) < rank(candidates[j])
+		}
+		return candidates[i].Path < candidates[j].Path
+	})
+	if len(candidates) > 1 {
+		logrus.Infof("Found %d manifests for %s/%s:%s, using %s", len(candidates), repo, imageName, imageTag, candidates[0].Path)
+	}
+	return candidates[0], nil
 }
 
 // runCommand executes a command and logs its output.
@@ -227,20 +1171,25 @@ func runCommand(cmdArgs []string) error {
 	logrus.Infof("Command output:\n%s\n", string(output))
 	if err != nil {
 		logrus.Errorf("Error executing command: %v", err)
-		return err
+		return commandFailure(err, output)
 	}
 	return nil
 }
 
-// runCommandAndCaptureOutput executes a command and captures its output as a string.
-func runCommandAndCaptureOutput(cmdArgs []string) (string, error) {
+// runCommandAndCaptureStdout executes a command and returns its standard output,
+// logging anything it writes to standard error.
+func runCommandAndCaptureStdout(cmdArgs []string) ([]byte, error) {
 	cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
-	output, err := cmd.CombinedOutput()
-
-	// Replace literal \n with actual newlines
-	formattedOutput := strings.ReplaceAll(string(output), "\\n", "\n")
-
-	return formattedOutput, err
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if stderr.Len() > 0 {
+		logrus.Infof("Command output:\n%s\n", stderr.String())
+	}
+	if err != nil {
+		return output, commandFailure(err, stderr.Bytes())
+	}
+	return output, nil
 }
 
 // setAuthParams sets authentication parameters for the command based on the provided args.
@@ -258,12 +1207,27 @@ func setAuthParams(cmdArgs []string, args Args) ([]string, error) {
 	return cmdArgs, nil
 }
 
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
 // parseDockerImage parses a Docker image string and returns the repo, imageName, and imageTag.
-func parseDockerImage(dockerImage string) (repo, imageName, imageTag string, err error) {
-	// Split by the last occurrence of ':'
+// When repoKey is set it is used as the repo instead of guessing it from the image path.
+// A leading registry host is skipped, see isRegistryHost.
+func parseDockerImage(dockerImage, repoKey string, hosts []string) (repo, imageName, imageTag string, err error) {
+	// Split by the last occurrence of ':' in the last path segment, as a colon
+	// before it belongs to the registry port. Like docker, default to latest.
 	lastColonIndex := strings.LastIndex(dockerImage, ":")
-	if lastColonIndex == -1 {
-		logrus.Errorf("invalid Docker image format: %s", dockerImage)
+	if lastColonIndex <= strings.LastIndex(dockerImage, "/") {
+		logrus.Warnf("Docker image %s has no tag, using latest", dockerImage)
+		dockerImage += ":latest"
+		lastColonIndex = len(dockerImage) - len(":latest")
 	}
 
 	imageTag = dockerImage[lastColonIndex+1:]
@@ -271,18 +1235,34 @@ func parseDockerImage(dockerImage string) (repo, imageName, imageTag string, err
 
 	// Split the image path by '/'
 	pathParts := strings.Split(imagePath, "/")
-	if len(pathParts) < 2 {
+	if len(pathParts) < 2 && repoKey == "" {
 		logrus.Errorf("invalid Docker image format: %s", dockerImage)
 	}
 
-	// Check if the first part is in the x.y.z format
-	isDomain := strings.Count(pathParts[0], ".") >= 2
+	// With an explicit repository key the image name follows the key, or the
+	// registry host when the key is not part of the path
+	if repoKey != "" {
+		if i := slices.Index(pathParts, repoKey); i >= 0 {
+			pathParts = pathParts[i+1:]
+		} else if isRegistryHost(pathParts[0], hosts) {
+			pathParts = pathParts[1:]
+		}
+		return repoKey, strings.Join(pathParts, "/"), imageTag, nil
+	}
+
+	// Check if the first part is a registry host. Anything after the repo is
+	// the image name, however deeply it is nested.
+	isDomain := isRegistryHost(pathParts[0], hosts)
 
 	// Extract repo and image name
-	if isDomain {
+	if isDomain && len(pathParts) > 2 {
 		// The repo is the part immediately after the domain
 		repo = pathParts[1]
 		imageName = strings.Join(pathParts[2:], "/")
+	} else if subdomainRepo := registrySubdomainRepo(pathParts[0], hosts); isDomain && subdomainRepo != "" {
+		// Registries using the subdomain method have no repo in the path
+		repo = subdomainRepo
+		imageName = pathParts[1]
 	} else {
 		repo = pathParts[0]
 		imageName = strings.Join(pathParts[1:], "/")
@@ -291,22 +1271,69 @@ func parseDockerImage(dockerImage string) (repo, imageName, imageTag string, err
 	return repo, imageName, imageTag, nil
 }
 
+// isRegistryHost reports whether the first segment of an image path is a
+// registry host rather than a repository, as docker decides it: hosts have a
+// dot or a port, or are localhost. Hosts without either, like the host of
+// http://artifactory/, are recognized when they are one of the known hosts.
+func isRegistryHost(segment string, hosts []string) bool {
+	return strings.ContainsAny(segment, ".:") || segment == "localhost" || slices.Contains(hosts, segment)
+}
+
+// registrySubdomainRepo returns the repository of a registry host using the
+// subdomain method of one of the known hosts, <repo>.<host> or, as JFrog
+// cloud names them, <server>-<repo>.<domain> for the host <server>.<domain>.
+// It returns "" for other hosts.
+func registrySubdomainRepo(segment string, hosts []string) string {
+	for _, host := range hosts {
+		if repo, ok := strings.CutSuffix(segment, "."+host); ok && repo != "" && !strings.Contains(repo, ".") {
+			return repo
+		}
+		server, domain, found := strings.Cut(host, ".")
+		if !found {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(segment, server+"-"); ok {
+			if repo, ok := strings.CutSuffix(rest, "."+domain); ok && repo != "" && !strings.Contains(repo, ".") {
+				return repo
+			}
+		}
+	}
+	return ""
+}
+
+// registryHosts returns the registry hosts the images of the Artifactory
+// instance are referenced by: the host of PLUGIN_URL and the registries of
+// PLUGIN_REGISTRY_MAP.
+func registryHosts(args Args) []string {
+	var hosts []string
+	if parsedURL, err := url.Parse(args.URL); err == nil && parsedURL.Hostname() != "" {
+		hosts = append(hosts, parsedURL.Hostname())
+	}
+	registries, _ := parseRegistryMap(args.RegistryMap)
+	for host := range registries {
+		hosts = append(hosts, host)
+	}
+	return hosts
+}
+
 // sanitizeURL trims the URL to include only up to the '/artifactory/' path.
 func sanitizeURL(inputURL string) (string, error) {
 	parsedURL, err := url.Parse(inputURL)
 	if err != nil {
-		logrus.Errorf("invalid URL: %s", inputURL)
+		return "", fmt.Errorf("invalid URL %s: %w", inputURL, err)
 	}
 	if parsedURL.Scheme == "" || parsedURL.Host == "" {
-		logrus.Errorf("invalid URL: %s", inputURL)
+		return "", fmt.Errorf("invalid URL %s: expected scheme and host", inputURL)
 	}
+	// URLs without the /artifactory context, like https://acme.jfrog.io, get it appended
 	parts := strings.Split(parsedURL.Path, "/artifactory")
 	if len(parts) < 2 {
-		logrus.Errorf("url does not contain '/artifactory': %s", inputURL)
+		logrus.Debugf("Appending /artifactory/ to URL %s", inputURL)
 	}
 
 	// Always set the path to the first part + "/artifactory/"
-	parsedURL.Path = parts[0] + "/artifactory/"
+	parsedURL.Path = strings.TrimRight(parts[0], "/") + "/artifactory/"
+	parsedURL.RawPath = ""
 
 	return parsedURL.String(), nil
 }
@@ -0,0 +1,110 @@
+package main
+
+import "testing"
+
+func TestSanitizeURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"bare host", "https://acme.jfrog.io", "https://acme.jfrog.io/artifactory/", false},
+		{"trailing slash", "https://acme.jfrog.io/", "https://acme.jfrog.io/artifactory/", false},
+		{"path with trailing slash", "https://x.io/ui/", "https://x.io/ui/artifactory/", false},
+		{"suffixed", "https://acme.jfrog.io/artifactory", "https://acme.jfrog.io/artifactory/", false},
+		{"suffixed with trailing slash", "https://acme.jfrog.io/artifactory/", "https://acme.jfrog.io/artifactory/", false},
+		{"suffixed with API path", "https://acme.jfrog.io/artifactory/api/build", "https://acme.jfrog.io/artifactory/", false},
+		{"port", "http://localhost:8081/artifactory/", "http://localhost:8081/artifactory/", false},
+		{"no scheme", "acme.jfrog.io", "", true},
+		{"unparsable", "://acme.jfrog.io", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sanitizeURL(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sanitizeURL(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("sanitizeURL(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDockerImage(t *testing.T) {
+	hosts := registryHosts(Args{URL: "https://acme.jfrog.io/artifactory", RegistryMap: "artifactory=docker-local"})
+	tests := []struct {
+		name     string
+		image    string
+		repoKey  string
+		wantRepo string
+		wantName string
+		wantTag  string
+	}{
+		{"host", "acme.jfrog.io/docker-local/app:1.0", "", "docker-local", "app", "1.0"},
+		{"no host", "docker-local/app:1.0", "", "docker-local", "app", "1.0"},
+		{"no tag", "acme.jfrog.io/docker-local/app", "", "docker-local", "app", "latest"},
+		{"host with port and no tag", "registry.local:5000/docker-local/app", "", "docker-local", "app", "latest"},
+		{"other dotted host", "docker.acme.com/docker-local/app:1", "", "docker-local", "app", "1"},
+		{"single dot host", "artifactory.internal/docker-local/app:1", "", "docker-local", "app", "1"},
+		{"localhost", "localhost/docker-local/app:1", "", "docker-local", "app", "1"},
+		{"known host without dot", "artifactory/docker-local/app:1", "", "docker-local", "app", "1"},
+		{"nested", "acme.jfrog.io/docker-local/team/project/service:2.1", "", "docker-local", "team/project/service", "2.1"},
+		{"nested without host", "docker-local/team/project/service:2.1", "", "docker-local", "team/project/service", "2.1"},
+		{"nested behind port", "registry.local:5000/docker-local/team/project/service:tag", "", "docker-local", "team/project/service", "tag"},
+		{"cloud subdomain", "acme-docker.jfrog.io/app:1", "", "docker", "app", "1"},
+		{"repo subdomain", "docker-local.acme.jfrog.io/app:1", "", "docker-local", "app", "1"},
+		{"repo key in path", "artifactory.internal/docker-local/team/app:1", "docker-local", "docker-local", "team/app", "1"},
+		{"repo key behind host", "docker.acme.com/team/app:1", "docker-local", "docker-local", "team/app", "1"},
+		{"repo key without host", "team/app:1", "docker-local", "docker-local", "team/app", "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, name, tag, err := parseDockerImage(tt.image, tt.repoKey, hosts)
+			if err != nil {
+				t.Fatalf("parseDockerImage(%q) error = %v", tt.image, err)
+			}
+			if repo != tt.wantRepo || name != tt.wantName || tag != tt.wantTag {
+				t.Errorf("parseDockerImage(%q) = %q, %q, %q, want %q, %q, %q", tt.image, repo, name, tag, tt.wantRepo, tt.wantName, tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestSelectManifest(t *testing.T) {
+	artifacts := []Artifact{
+		{Path: "docker-local/app/1.0/list.manifest.json"},
+		{Path: "docker-local/app/1.0/manifest.json"},
+		{Path: "docker-local/app/1.0/oci-manifest.json"},
+		{Path: "docker-local/app/retagged/manifest.json"},
+		{Path: "other-local/app/1.0/manifest.json"},
+	}
+	tests := []struct {
+		name          string
+		manifestNames string
+		searchPath    string
+		want          string
+	}{
+		{"default names", "manifest.json,list.manifest.json", "app/1.0", "docker-local/app/1.0/manifest.json"},
+		{"manifest list first", "list.manifest.json,manifest.json", "app/1.0", "docker-local/app/1.0/list.manifest.json"},
+		{"pattern", "oci-*.json,manifest.json", "app/1.0", "docker-local/app/1.0/oci-manifest.json"},
+		{"unlisted name last", "manifest.json", "app/1.0", "docker-local/app/1.0/manifest.json"},
+		{"searched path before name", "list.manifest.json,manifest.json", "app/retagged", "docker-local/app/retagged/manifest.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectManifest(artifacts, tt.manifestNames, "docker-local", tt.searchPath, "app", "1.0")
+			if err != nil {
+				t.Fatalf("selectManifest() error = %v", err)
+			}
+			if got.Path != tt.want {
+				t.Errorf("selectManifest() = %q, want %q", got.Path, tt.want)
+			}
+		})
+	}
+
+	if _, err := selectManifest(artifacts, "manifest.json", "missing-local", "app/1.0", "app", "1.0"); err != errManifestNotFound {
+		t.Errorf("selectManifest() error = %v, want %v", err, errManifestNotFound)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// imageManifest is the part of a Docker or OCI image manifest, or of a manifest
+// list/index, that the plugin reads.
+type imageManifest struct {
+	SchemaVersion int                  `json:"schemaVersion"`
+	MediaType     string               `json:"mediaType"`
+	ArtifactType  string               `json:"artifactType"`
+	Config        manifestDescriptor   `json:"config"`
+	Layers        []manifestDescriptor `json:"layers"`
+	Manifests     []manifestDescriptor `json:"manifests"`
+	Annotations   map[string]string    `json:"annotations"`
+
+	// raw is the manifest as stored in Artifactory
+	raw []byte
+}
+
+// manifestDescriptor references a blob or manifest by digest.
+type manifestDescriptor struct {
+	MediaType    string `json:"mediaType"`
+	ArtifactType string `json:"artifactType"`
+	Digest       string `json:"digest"`
+	Size         int64  `json:"size"`
+}
+
+// fetchManifest downloads and parses the manifest stored at manifestPath, which
+// includes the repository key.
+func fetchManifest(ctx context.Context, args Args, sanitizedURL, manifestPath string) (*imageManifest, error) {
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+escapeItemPath(manifestPath), nil, args)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading manifest %s: %w", manifestPath, err)
+	}
+
+	manifest := imageManifest{raw: body}
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("error parsing manifest %s: %w", manifestPath, err)
+	}
+	return &manifest, nil
+}
+
+// layerDependencies returns a build info dependency for each layer of the
+// manifest, identified by digest and typed by media type. Build info
+// dependencies have no size field, so sizes are not recorded.
+func layerDependencies(manifest *imageManifest) []map[string]interface{} {
+	var dependencies []map[string]interface{}
+	for _, layer := range manifest.Layers {
+		dependencies = append(dependencies, map[string]interface{}{
+			"id":     layer.Digest,
+			"type":   layer.MediaType,
+			"sha256": strings.TrimPrefix(layer.Digest, "sha256:"),
+		})
+	}
+	return dependencies
+}
+
+// imageStats returns the total compressed size of the layers and the layer
+// count of the manifest as build properties.
+func imageStats(manifest *imageManifest) map[string]string {
+	var size int64
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+	return map[string]string{
+		"docker.image.size":   strconv.FormatInt(size, 10),
+		"docker.image.layers": strconv.Itoa(len(manifest.Layers)),
+	}
+}
+
+// fetchImageLabels downloads the config blob referenced by the manifest and
+// returns the image labels. Manifest lists have no config of their own, so no
+// labels are returned for them.
+func fetchImageLabels(ctx context.Context, args Args, sanitizedURL, manifestPath string, manifest *imageManifest) (map[string]string, error) {
+	if manifest.Config.Digest == "" {
+		return nil, warnOrFail(args, "Manifest %s has no config blob to read labels from", manifestPath)
+	}
+
+	// Blobs are stored next to the manifest, named after their digest
+	blobPath := path.Join(path.Dir(manifestPath), strings.Replace(manifest.Config.Digest, ":", "__", 1))
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+escapeItemPath(blobPath), nil, args)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return nil, fmt.Errorf("error downloading image config %s: %w", blobPath, err)
+	}
+
+	var config struct {
+		Config struct {
+			Labels map[string]string `json:"Labels"`
+		} `json:"config"`
+	}
+	if err := json.Unmarshal(body, &config); err != nil {
+		return nil, fmt.Errorf("error parsing image config %s: %w", blobPath, err)
+	}
+	return config.Config.Labels, nil
+}
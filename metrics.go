@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const metricsJob = "drone_artifactory_docker_buildinfo"
+
+// pushMetrics pushes the phase durations, the retries and the outcome of the run to a Prometheus
+// pushgateway, grouped by repository and build name. It is a no-op when no
+// pushgateway is configured.
+func pushMetrics(ctx context.Context, args Args, repo string, t *tracer, runErr error) error {
+	if args.PushgatewayURL == "" {
+		return nil
+	}
+
+	var body bytes.Buffer
+	body.WriteString("# TYPE artifactory_buildinfo_phase_duration_seconds gauge\n")
+	for _, s := range t.spans {
+		fmt.Fprintf(&body, "artifactory_buildinfo_phase_duration_seconds{phase=%q} %f\n", s.name, s.end.Sub(s.start).Seconds())
+	}
+	// Each push replaces the values of the previous run, so counts are gauges
+	body.WriteString("# TYPE artifactory_buildinfo_phase_failures gauge\n")
+	for _, s := range t.spans {
+		fmt.Fprintf(&body, "artifactory_buildinfo_phase_failures{phase=%q} %d\n", s.name, boolToInt(s.err != nil))
+	}
+	body.WriteString("# TYPE artifactory_buildinfo_retries gauge\n")
+	retryCounts.Lock()
+	var actions []string
+	for action := range retryCounts.byAction {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	for _, action := range actions {
+		fmt.Fprintf(&body, "artifactory_buildinfo_retries{action=%q} %d\n", action, retryCounts.byAction[action])
+	}
+	retryCounts.Unlock()
+	body.WriteString("# TYPE artifactory_buildinfo_duration_seconds gauge\n")
+	fmt.Fprintf(&body, "artifactory_buildinfo_duration_seconds %f\n", time.Since(t.root.start).Seconds())
+	body.WriteString("# TYPE artifactory_buildinfo_success gauge\n")
+	fmt.Fprintf(&body, "artifactory_buildinfo_success %d\n", boolToInt(runErr == nil))
+	body.WriteString("# TYPE artifactory_buildinfo_last_run_timestamp_seconds gauge\n")
+	fmt.Fprintf(&body, "artifactory_buildinfo_last_run_timestamp_seconds %d\n", time.Now().Unix())
+
+	// Label values are base64 encoded so build names containing slashes stay
+	// within a single path segment
+	endpoint := strings.TrimSuffix(args.PushgatewayURL, "/") +
+		"/metrics/job/" + metricsJob +
+		"/repo@base64/" + base64.RawURLEncoding.EncodeToString([]byte(repo)) +
+		"/build_name@base64/" + base64.RawURLEncoding.EncodeToString([]byte(args.BuildName))
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; version=0.0.4")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("pushgateway returned %s", resp.Status)
+	}
+	return nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
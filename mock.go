@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+)
+
+// mockConfigBlob is the image config every mock manifest references.
+var mockConfigBlob = []byte("{}")
+
+// mockArtifactory is an in-process fake of the Artifactory endpoints the
+// plugin and the jfrog CLI use, for testing pipeline wiring with PLUGIN_MOCK
+// without a real instance. Every image searched for exists, with a manifest
+// and an empty config, and published builds are kept in memory.
+type mockArtifactory struct {
+	mu     sync.Mutex
+	builds map[string][]byte
+}
+
+// startMockArtifactory starts the fake Artifactory. Its URL is the server URL
+// followed by /artifactory/.
+func startMockArtifactory() *httptest.Server {
+	m := &mockArtifactory{builds: map[string][]byte{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /artifactory/api/system/ping", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "OK")
+	})
+	mux.HandleFunc("GET /artifactory/api/system/version", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, map[string]string{"version": "7.90.0", "revision": "mock"})
+	})
+	mux.HandleFunc("GET /artifactory/api/repositories/{repo}", func(w http.ResponseWriter, r *http.Request) {
+		writeMockJSON(w, map[string]string{"key": r.PathValue("repo"), "rclass": "local", "packageType": "docker"})
+	})
+	mux.HandleFunc("POST /artifactory/api/search/aql", m.search)
+	mux.HandleFunc("PUT /artifactory/api/build", m.putBuild)
+	mux.HandleFunc("GET /artifactory/api/build/{name}/{number}", m.getBuild)
+	mux.HandleFunc("GET /artifactory/api/storage/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := mockFile(r.PathValue("path"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		writeMockJSON(w, map[string]interface{}{"checksums": mockChecksums(content)})
+	})
+	mux.HandleFunc("GET /artifactory/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		content, ok := mockFile(r.PathValue("path"))
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(content)
+	})
+	// Setting properties and other writes are accepted and ignored
+	mux.HandleFunc("PUT /artifactory/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return httptest.NewServer(mux)
+}
+
+// search answers an AQL items.find query with the manifest and config of the
+// image under the queried repository and path.
+func (m *mockArtifactory) search(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	_, query, _ := strings.Cut(string(body), "items.find(")
+	var criteria interface{}
+	if err := json.NewDecoder(strings.NewReader(query)).Decode(&criteria); err != nil {
+		http.Error(w, "invalid AQL query", http.StatusBadRequest)
+		return
+	}
+	repo := findCriterion(criteria, "repo")
+	dir := strings.Trim(strings.ReplaceAll(findCriterion(criteria, "path"), "*", ""), "/")
+
+	names := findCriteria(criteria, "name")
+
+	results := []map[string]interface{}{}
+	if repo != "" && dir != "" {
+		for _, name := range []string{"manifest.json", "sha256__" + sha256Hex(mockConfigBlob)} {
+			if len(names) > 0 && !matchesAny(name, names) {
+				continue
+			}
+			content, _ := mockFile(repo + "/" + dir + "/" + name)
+			checksums := mockChecksums(content)
+			results = append(results, map[string]interface{}{
+				"repo": repo, "path": dir, "name": name, "type": "file", "size": len(content),
+				"sha256": checksums["sha256"], "actual_sha1": checksums["sha1"], "actual_md5": checksums["md5"],
+			})
+		}
+	}
+	writeMockJSON(w, map[string]interface{}{"results": results})
+}
+
+func (m *mockArtifactory) putBuild(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var build struct {
+		Name   string `json:"name"`
+		Number string `json:"number"`
+	}
+	if err := json.Unmarshal(body, &build); err != nil {
+		http.Error(w, "invalid build info", http.StatusBadRequest)
+		return
+	}
+	m.mu.Lock()
+	m.builds[build.Name+"/"+build.Number] = body
+	m.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *mockArtifactory) getBuild(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	build, ok := m.builds[r.PathValue("name")+"/"+r.PathValue("number")]
+	m.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	writeMockJSON(w, map[string]json.RawMessage{"buildInfo": build})
+}
+
+// mockFile returns the content of a manifest or the config blob.
+func mockFile(filePath string) ([]byte, bool) {
+	switch name := path.Base(filePath); {
+	case name == "manifest.json":
+		manifest, _ := json.Marshal(map[string]interface{}{
+			"schemaVersion": 2,
+			"mediaType":     "application/vnd.docker.distribution.manifest.v2+json",
+			"config": map[string]interface{}{
+				"mediaType": "application/vnd.docker.container.image.v1+json",
+				"size":      len(mockConfigBlob),
+				"digest":    "sha256:" + sha256Hex(mockConfigBlob),
+			},
+			"layers": []interface{}{},
+		})
+		return manifest, true
+	case name == "sha256__"+sha256Hex(mockConfigBlob):
+		return mockConfigBlob, true
+	}
+	return nil, false
+}
+
+// findCriterion returns the first value of the field in the AQL criteria.
+func findCriterion(criteria interface{}, field string) string {
+	if values := findCriteria(criteria, field); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// findCriteria returns the values of the field in the AQL criteria, given
+// directly or as a $match or $eq comparison.
+func findCriteria(criteria interface{}, field string) []string {
+	var values []string
+	switch c := criteria.(type) {
+	case map[string]interface{}:
+		switch value := c[field].(type) {
+		case string:
+			values = append(values, value)
+		case map[string]interface{}:
+			for _, op := range []string{"$match", "$eq"} {
+				if s, ok := value[op].(string); ok {
+					values = append(values, s)
+				}
+			}
+		}
+		for key, nested := range c {
+			if key != field {
+				values = append(values, findCriteria(nested, field)...)
+			}
+		}
+	case []interface{}:
+		for _, nested := range c {
+			values = append(values, findCriteria(nested, field)...)
+		}
+	}
+	return values
+}
+
+func mockChecksums(content []byte) map[string]string {
+	sha1Sum, md5Sum := sha1.Sum(content), md5.Sum(content)
+	return map[string]string{
+		"sha256": sha256Hex(content),
+		"sha1":   hex.EncodeToString(sha1Sum[:]),
+		"md5":    hex.EncodeToString(md5Sum[:]),
+	}
+}
+
+func sha256Hex(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeMockJSON(w http.ResponseWriter, value interface{}) {
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(value); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body.Bytes())
+}
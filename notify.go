@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// publishSummary describes a published build for notifications.
+type publishSummary struct {
+	Images    []dockerImage
+	BuildName string
+	Number    string
+	Link      string
+}
+
+// buildUILink returns the URL of the published build in the JFrog UI, in the
+// format the jfrog CLI prints after publishing.
+func buildUILink(args Args, sanitizedURL string, started time.Time) string {
+	platformURL := strings.TrimSuffix(sanitizedURL, "artifactory/")
+	name, number := escapeBuildSegment(args.BuildName), escapeBuildSegment(args.BuildNumber)
+	if legacyAPI(args) {
+		return fmt.Sprintf("%sartifactory/webapp/#/builds/%s/%s", platformURL, name, number)
+	}
+	link := fmt.Sprintf("%sui/builds/%s/%s/%s/published?buildRepo=%s", platformURL, name, number, strconv.FormatInt(started.UnixMilli(), 10), url.QueryEscape(buildInfoRepo(args)))
+	if project := buildInfoProject(args); project != "" {
+		link += "&projectKey=" + url.QueryEscape(project)
+	}
+	return link
+}
+
+// publishedBuildLink fetches the published build to read its start time, which
+// is part of the UI link.
+func publishedBuildLink(ctx context.Context, args Args, sanitizedURL string) (string, error) {
+	buildInfo, err := fetchBuildInfo(ctx, args, sanitizedURL)
+	if err != nil {
+		return "", err
+	}
+	startedValue, _ := buildInfo["started"].(string)
+	started, err := time.Parse(buildInfoTimeFormat, startedValue)
+	if err != nil {
+		return "", fmt.Errorf("error parsing build start time %q: %w", startedValue, err)
+	}
+	return buildUILink(args, sanitizedURL, started), nil
+}
+
+// sendNotifications posts the summary to the configured Slack and Microsoft
+// Teams webhooks. Failures are logged and do not fail the step.
+func sendNotifications(ctx context.Context, args Args, summary publishSummary) {
+	if args.SlackWebhook != "" {
+		if err := postWebhook(ctx, args.SlackWebhook, slackMessage(summary)); err != nil {
+			logrus.Warnf("error sending Slack notification: %v", err)
+		}
+	}
+	if args.TeamsWebhook != "" {
+		if err := postWebhook(ctx, args.TeamsWebhook, teamsMessage(summary)); err != nil {
+			logrus.Warnf("error sending Microsoft Teams notification: %v", err)
+		}
+	}
+}
+
+func slackMessage(summary publishSummary) map[string]interface{} {
+	lines := []string{fmt.Sprintf("*Build info published:* %s #%s", summary.BuildName, summary.Number)}
+	for _, img := range summary.Images {
+		lines = append(lines, fmt.Sprintf("*Image:* `%s` *Digest:* `%s`", img.Ref, img.digest()))
+	}
+	if summary.Link != "" {
+		lines = append(lines, fmt.Sprintf("<%s|View build in Artifactory>", summary.Link))
+	}
+	return map[string]interface{}{"text": strings.Join(lines, "\n")}
+}
+
+func teamsMessage(summary publishSummary) map[string]interface{} {
+	var facts []interface{}
+	for _, img := range summary.Images {
+		facts = append(facts, map[string]string{"name": img.Ref, "value": img.digest()})
+	}
+	message := map[string]interface{}{
+		"@type":    "MessageCard",
+		"@context": "http://schema.org/extensions",
+		"summary":  "Build info published: " + summary.BuildName + " #" + summary.Number,
+		"title":    "Build info published: " + summary.BuildName + " #" + summary.Number,
+		"sections": []interface{}{
+			map[string]interface{}{
+				"facts": facts,
+			},
+		},
+	}
+	if summary.Link != "" {
+		message["potentialAction"] = []interface{}{
+			map[string]interface{}{
+				"@type":   "OpenUri",
+				"name":    "View build in Artifactory",
+				"targets": []interface{}{map[string]string{"os": "default", "uri": summary.Link}},
+			},
+		}
+	}
+	return message
+}
+
+// postWebhook posts the message as JSON to an incoming webhook.
+func postWebhook(ctx context.Context, webhook string, message interface{}) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ociArtifactModule returns a build-info module for an OCI artifact pushed with
+// ORAS or a similar client, such as a WASM module or a policy bundle. The
+// jfrog CLI only understands container images, so the module is assembled from
+// the manifest: the manifest and its layers are recorded as artifacts, and the
+// artifact type as a property.
+func ociArtifactModule(ctx context.Context, args Args, sanitizedURL string, img dockerImage) (map[string]interface{}, error) {
+	manifest := img.manifest
+	artifacts, err := manifestArtifacts(ctx, args, sanitizedURL, img.Manifest.Path, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		// OCI artifacts other than images have no build-info module type of their own
+		"type": "generic",
+		"id":   img.moduleID(),
+		"properties": map[string]interface{}{
+			"oci.artifact.type": firstNonEmpty(manifest.ArtifactType, manifest.Config.MediaType),
+		},
+		"artifacts": artifacts,
+	}, nil
+}
+
+// manifestArtifacts returns build-info artifacts for a manifest and its layers,
+// with the checksums Artifactory stored for them.
+func manifestArtifacts(ctx context.Context, args Args, sanitizedURL, manifestPath string, manifest *imageManifest) ([]map[string]interface{}, error) {
+	// Blobs are stored next to the manifest, named after their digest, and are
+	// typed by their media type
+	type ociFile struct{ name, mediaType string }
+	files := []ociFile{{path.Base(manifestPath), "json"}}
+	for _, layer := range manifest.Layers {
+		files = append(files, ociFile{strings.Replace(layer.Digest, ":", "__", 1), layer.MediaType})
+	}
+
+	var artifacts []map[string]interface{}
+	for _, file := range files {
+		filePath := path.Dir(manifestPath) + "/" + file.name
+		sums, err := getChecksums(ctx, args, sanitizedURL, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", filePath, err)
+		}
+		repo, itemPath, _ := strings.Cut(filePath, "/")
+		artifacts = append(artifacts, map[string]interface{}{
+			"type":                   file.mediaType,
+			"name":                   file.name,
+			"path":                   itemPath,
+			"originalDeploymentRepo": repo,
+			"sha1":                   sums.Sha1,
+			"sha256":                 sums.Sha256,
+			"md5":                    sums.Md5,
+		})
+	}
+	return artifacts, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// writeOutputs appends the values to the step output file Harness and Drone
+// pass in DRONE_OUTPUT, so later steps can read them as step outputs. It is a
+// no-op outside of a pipeline that collects outputs.
+func writeOutputs(values map[string]string) error {
+	outputFile := os.Getenv("DRONE_OUTPUT")
+	if outputFile == "" {
+		return nil
+	}
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, key := range keys {
+		// Outputs are single lines of KEY=value
+		fmt.Fprintf(&b, "%s=%s\n", key, strings.ReplaceAll(values[key], "\n", " "))
+	}
+
+	f, err := os.OpenFile(outputFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening output file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf("error writing output file: %w", err)
+	}
+	return nil
+}
+
+// saveManifest writes the manifest of the image with the given index to the
+// manifest file, relative to the workspace unless the path is absolute, and
+// returns the path written. The index is added to the file name of all but the
+// first image.
+func saveManifest(args Args, manifest *imageManifest, index int) (string, error) {
+	manifestFile := args.ManifestFile
+	if index > 0 {
+		ext := filepath.Ext(manifestFile)
+		manifestFile = strings.TrimSuffix(manifestFile, ext) + "-" + strconv.Itoa(index+1) + ext
+	}
+	if !filepath.IsAbs(manifestFile) && args.DefaultPath != "" {
+		manifestFile = filepath.Join(args.DefaultPath, manifestFile)
+	}
+	if err := os.WriteFile(manifestFile, manifest.raw, 0o644); err != nil {
+		return "", fmt.Errorf("error saving manifest: %w", err)
+	}
+	return manifestFile, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// appendToParentBuild appends the published build to the parent build in the
+// jfrog CLI build partials. A later step with PLUGIN_MODE publish and the
+// parent's name and number publishes the parent, referencing every build
+// appended to it.
+func appendToParentBuild(args Args, sanitizedURL string) error {
+	logrus.Infof("Appending the build to parent build %s #%s", args.ParentBuildName, args.ParentBuildNumber)
+	// build-append reads the published build info from a configured server
+	if err := configureServer(args, sanitizedURL); err != nil {
+		return err
+	}
+	cmdArgs := []string{"jfrog", "rt", "build-append", args.ParentBuildName, args.ParentBuildNumber, args.BuildName, args.BuildNumber, "--server-id=" + issuesServerID}
+	if err := runCommand(appendProjectFlag(cmdArgs, args)); err != nil {
+		return fmt.Errorf("error executing jfrog rt build-append command: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// parseBuildInfoPatch parses the JSON merge patch of PLUGIN_BUILDINFO_PATCH.
+func parseBuildInfoPatch(value string) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.UseNumber()
+	var patch map[string]interface{}
+	if err := decoder.Decode(&patch); err != nil {
+		return nil, fmt.Errorf("error parsing build info patch: %w", err)
+	}
+	if patch == nil {
+		return nil, errors.New("build info patch needs to be a JSON object")
+	}
+	return patch, nil
+}
+
+// applyMergePatch returns an edit that applies a JSON merge patch (RFC 7396)
+// to the build info: objects are merged, null removes a field and any other
+// value replaces it.
+func applyMergePatch(patch map[string]interface{}) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		mergeObject(buildInfo, patch)
+		return nil
+	}
+}
+
+// mergeObject merges the patch into the target object.
+func mergeObject(target, patch map[string]interface{}) {
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		patchObject, ok := value.(map[string]interface{})
+		if !ok {
+			target[key] = value
+			continue
+		}
+		targetObject, ok := target[key].(map[string]interface{})
+		if !ok {
+			targetObject = map[string]interface{}{}
+		}
+		mergeObject(targetObject, patchObject)
+		target[key] = targetObject
+	}
+}
+
+// createMergePatch returns the JSON merge patch that turns before into after.
+// Objects are compared field by field, any other changed value is replaced.
+func createMergePatch(before, after map[string]interface{}) map[string]interface{} {
+	patch := map[string]interface{}{}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			patch[key] = nil
+		}
+	}
+	for key, value := range after {
+		if reflect.DeepEqual(before[key], value) {
+			continue
+		}
+		beforeObject, beforeOK := before[key].(map[string]interface{})
+		afterObject, afterOK := value.(map[string]interface{})
+		if beforeOK && afterOK {
+			patch[key] = createMergePatch(beforeObject, afterObject)
+			continue
+		}
+		patch[key] = value
+	}
+	return patch
+}
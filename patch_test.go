@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestMergeObject runs the examples of RFC 7396 appendix A.
+func TestMergeObject(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		want   string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tt := range tests {
+		var target, patch, want map[string]interface{}
+		for _, decode := range []struct {
+			value string
+			into  *map[string]interface{}
+		}{{tt.target, &target}, {tt.patch, &patch}, {tt.want, &want}} {
+			if err := json.Unmarshal([]byte(decode.value), decode.into); err != nil {
+				t.Fatal(err)
+			}
+		}
+		mergeObject(target, patch)
+		if !reflect.DeepEqual(target, want) {
+			t.Errorf("merging %s into %s = %v, want %s", tt.patch, tt.target, target, tt.want)
+		}
+	}
+}
+
+func TestParseBuildInfoPatch(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{`{"principal":"svc","properties":{"team":"core"}}`, false},
+		{`{}`, false},
+		{`null`, true},
+		{`["principal"]`, true},
+		{`{"principal":`, true},
+	}
+	for _, tt := range tests {
+		_, err := parseBuildInfoPatch(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseBuildInfoPatch(%s) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
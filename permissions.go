@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// permissionNames are the effective permission codes of the storage API.
+var permissionNames = map[string]string{
+	"r": "read",
+	"w": "deploy",
+	"n": "annotate",
+	"d": "delete",
+}
+
+// checkPermissions verifies that the credentials are accepted and, with
+// PLUGIN_EFFECTIVE_PERMISSION_CHECK, have the permissions the run needs on the
+// image repositories and the build-info repository, so permission problems
+// fail the step before any work is done. Effective permissions are only
+// visible to users with manage permission, which CI tokens rarely have, so
+// those checks are opt-in and skipped with a warning when Artifactory does not
+// return them.
+func checkPermissions(ctx context.Context, args Args, sanitizedURL string, repos []string) error {
+	if _, err := checkAuthentication(ctx, args, sanitizedURL); err != nil {
+		var httpErr *httpError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusUnauthorized {
+			return errors.New("the credentials were rejected by Artifactory")
+		}
+		return fmt.Errorf("error checking credentials: %w", err)
+	}
+	if !args.EffectivePermissions {
+		return nil
+	}
+
+	user, err := currentUser(ctx, args, sanitizedURL)
+	if err != nil {
+		logrus.Warnf("Skipping the permission check, the user of the credentials is unknown: %v", err)
+		return nil
+	}
+	if user == "" {
+		logrus.Warn("Skipping the permission check, the user of the credentials is unknown")
+		return nil
+	}
+
+	repoPermissions := []string{"r"}
+	if args.ArtifactBuildProperties != "" || args.LabelProperties == "artifact" || args.LabelProperties == "both" {
+		repoPermissions = append(repoPermissions, "n")
+	}
+	if args.TargetAction == "move" || args.PromoteTargetRepo != "" && !args.PromoteCopy {
+		repoPermissions = append(repoPermissions, "d")
+	}
+	for _, repo := range repos {
+		if err := checkEffectivePermissions(ctx, args, sanitizedURL, user, repo, repoPermissions); err != nil {
+			return err
+		}
+	}
+	for _, target := range []string{args.TargetRepo, args.PromoteTargetRepo} {
+		if target == "" {
+			continue
+		}
+		if err := checkEffectivePermissions(ctx, args, sanitizedURL, user, target, []string{"w"}); err != nil {
+			return err
+		}
+	}
+	// Before Artifactory 7 build permissions are not granted through a repository
+	if legacyAPI(args) {
+		return nil
+	}
+	return checkEffectivePermissions(ctx, args, sanitizedURL, user, buildInfoRepo(args), []string{"w"})
+}
+
+// currentUser returns the name of the user the credentials belong to.
+func currentUser(ctx context.Context, args Args, sanitizedURL string) (string, error) {
+	if args.Username != "" {
+		return args.Username, nil
+	}
+	// The access token API was introduced in Artifactory 7
+	if args.AccessToken == "" || legacyAPI(args) {
+		return "", nil
+	}
+
+	// The token API is served by the platform, outside of the /artifactory context
+	platformURL := strings.TrimSuffix(sanitizedURL, "artifactory/")
+	req, err := newRequest(ctx, http.MethodGet, platformURL+"access/api/v1/tokens/me", nil, args)
+	if err != nil {
+		return "", err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return "", err
+	}
+	var token struct {
+		Subject string `json:"subject"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", fmt.Errorf("error parsing token info: %w", err)
+	}
+	// Subjects look like jfac@<id>/users/<name>
+	_, user, _ := strings.Cut(token.Subject, "/users/")
+	return user, nil
+}
+
+// checkEffectivePermissions returns an error naming the permissions the user
+// lacks on the repository, counting those granted to the user directly and
+// through the groups the user is a member of.
+func checkEffectivePermissions(ctx context.Context, args Args, sanitizedURL, user, repo string, required []string) error {
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+"api/storage/"+url.PathEscape(repo)+"?permissions", nil, args)
+	if err != nil {
+		return err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		logrus.Warnf("Skipping the permission check on %s, effective permissions are not available: %v", repo, err)
+		return nil
+	}
+
+	var permissions struct {
+		Principals struct {
+			Users  map[string][]string `json:"users"`
+			Groups map[string][]string `json:"groups"`
+		} `json:"principals"`
+	}
+	if err := json.Unmarshal(body, &permissions); err != nil {
+		return fmt.Errorf("error parsing effective permissions: %w", err)
+	}
+	granted, listed := permissions.Principals.Users[user]
+	missing := missingPermissions(granted, required)
+
+	// Add the permissions granted through the groups of the user
+	if len(missing) > 0 && len(permissions.Principals.Groups) > 0 {
+		groups, err := userGroups(ctx, args, sanitizedURL, user)
+		if err != nil {
+			logrus.Warnf("Skipping the permission check on %s, permissions may be granted through a group of user %s: %v", repo, user, err)
+			return nil
+		}
+		for _, group := range groups {
+			if groupGranted, ok := permissions.Principals.Groups[group]; ok {
+				granted = append(granted, groupGranted...)
+				listed = true
+			}
+		}
+		missing = missingPermissions(granted, required)
+	}
+
+	if !listed {
+		logrus.Warnf("Skipping the permission check on %s, no permissions are listed for user %s", repo, user)
+		return nil
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("user %s is missing %s permission on repository %s", user, strings.Join(missing, " and "), repo)
+	}
+	return nil
+}
+
+// missingPermissions returns the names of the required permissions that are
+// not granted.
+func missingPermissions(granted, required []string) []string {
+	var missing []string
+	for _, p := range required {
+		if !slices.Contains(granted, p) {
+			missing = append(missing, permissionNames[p])
+		}
+	}
+	return missing
+}
+
+// userGroups returns the names of the groups the user is a member of.
+func userGroups(ctx context.Context, args Args, sanitizedURL, user string) ([]string, error) {
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+"api/security/users/"+url.PathEscape(user), nil, args)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return nil, err
+	}
+	var details struct {
+		Groups []string `json:"groups"`
+	}
+	if err := json.Unmarshal(body, &details); err != nil {
+		return nil, fmt.Errorf("error parsing user details: %w", err)
+	}
+	return details.Groups, nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import "os"
+
+// pipelineProperties returns build-info properties identifying the pipeline
+// execution that produced the build, read from the variables Harness CI and
+// Drone set. Variables that are not set are left out.
+func pipelineProperties() map[string]string {
+	sources := []struct {
+		property string
+		envs     []string
+	}{
+		{"harness.account.id", []string{"HARNESS_ACCOUNT_ID"}},
+		{"harness.org.id", []string{"HARNESS_ORG_ID"}},
+		{"harness.project.id", []string{"HARNESS_PROJECT_ID"}},
+		{"harness.pipeline.id", []string{"HARNESS_PIPELINE_ID"}},
+		{"harness.execution.id", []string{"HARNESS_EXECUTION_ID"}},
+		{"harness.stage.id", []string{"HARNESS_STAGE_ID"}},
+		{"harness.stage.name", []string{"DRONE_STAGE_NAME"}},
+		{"harness.step.name", []string{"DRONE_STEP_NAME"}},
+		// Harness sets DRONE_BUILD_EVENT to the trigger type of the execution
+		{"harness.trigger.type", []string{"HARNESS_TRIGGER_TYPE", "DRONE_BUILD_EVENT"}},
+	}
+
+	properties := map[string]string{}
+	for _, source := range sources {
+		for _, env := range source.envs {
+			if value := os.Getenv(env); value != "" {
+				properties[source.property] = value
+				break
+			}
+		}
+	}
+	return properties
+}
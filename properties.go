@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// setArtifactProperties sets properties on an artifact in Artifactory.
+func setArtifactProperties(args Args, sanitizedURL, artifactPath string, properties map[string]string) error {
+	cmdArgs := []string{"jfrog", "rt", "set-props", artifactPath, formatProperties(properties), "--url=" + sanitizedURL}
+	cmdArgs, err := setAuthParams(cmdArgs, args)
+	if err != nil {
+		return err
+	}
+	if err := runCommand(cmdArgs); err != nil {
+		return fmt.Errorf("error executing jfrog rt set-props command: %w", err)
+	}
+	return nil
+}
+
+// formatProperties formats properties in the "key1=value1;key2=value2" form the
+// jfrog CLI expects, escaping separators that appear in values.
+func formatProperties(properties map[string]string) string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	escaper := strings.NewReplacer(";", `\;`, ",", `\,`)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+escaper.Replace(properties[key]))
+	}
+	return strings.Join(pairs, ";")
+}
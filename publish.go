@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PublishCollected publishes the build that earlier steps created with
+// PLUGIN_SKIP_PUBLISH, from the build partials in the shared jfrog CLI home
+// directory. The build info edits the earlier steps saved are applied to the
+// build the jfrog CLI assembles.
+func PublishCollected(ctx context.Context, args Args, sanitizedURL string) error {
+	edits, err := loadBuildInfoEdits(args)
+	if err != nil {
+		return err
+	}
+	if err := publishBuildInfo(ctx, args, sanitizedURL, edits); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(buildInfoEditsDir(args)); err != nil {
+		logrus.Warnf("error removing the saved build info edits: %v", err)
+	}
+
+	link, err := publishedBuildLink(ctx, args, sanitizedURL)
+	if err != nil {
+		logrus.Warnf("error building the build link: %v", err)
+		return nil
+	}
+	logrus.Infof("Build info published at %s", link)
+	if err := writeOutputs(map[string]string{"BUILD_INFO_LINK": link}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// buildInfoDelta is what the build info edits of a PLUGIN_SKIP_PUBLISH step
+// change in the build the jfrog CLI assembles, saved for the step that
+// publishes the build.
+type buildInfoDelta struct {
+	// Patch is the merge patch of the fields other than the modules
+	Patch map[string]interface{} `json:"patch,omitempty"`
+	// Modules are the added and edited modules, replacing those with the same ID
+	Modules []map[string]interface{} `json:"modules,omitempty"`
+	// RemovedModules are the IDs of the modules the edits removed or renamed
+	RemovedModules []string `json:"removedModules,omitempty"`
+}
+
+// buildInfoEditsDir returns the directory in the jfrog CLI home directory the
+// edits of the build are saved in, one file per step.
+func buildInfoEditsDir(args Args) string {
+	sum := sha256.Sum256([]byte(buildInfoProject(args) + "\x00" + args.BuildName + "\x00" + args.BuildNumber))
+	return filepath.Join(os.Getenv("JFROG_CLI_HOME_DIR"), "buildinfo-edits", hex.EncodeToString(sum[:16]))
+}
+
+// saveBuildInfoEdits saves what the edits change in the build assembled so far,
+// for a later step with PLUGIN_MODE publish to apply.
+func saveBuildInfoEdits(args Args, sanitizedURL string, edits []buildInfoEdit) error {
+	before, err := assembleBuildInfo(buildPublishArgs(args, sanitizedURL))
+	if err != nil {
+		return err
+	}
+	body, err := encodeBuildInfo(before)
+	if err != nil {
+		return fmt.Errorf("error encoding build info: %w", err)
+	}
+	after, err := decodeBuildInfo(body)
+	if err != nil {
+		return fmt.Errorf("error copying build info: %w", err)
+	}
+	for _, edit := range edits {
+		if err := edit(after); err != nil {
+			return err
+		}
+	}
+
+	delta := diffBuildInfo(before, after)
+	if len(delta.Patch) == 0 && len(delta.Modules) == 0 && len(delta.RemovedModules) == 0 {
+		return nil
+	}
+	dir := buildInfoEditsDir(args)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("error creating build info edits directory: %w", err)
+	}
+	// Files are named by time, so the edits are applied in the order of the steps
+	file, err := os.CreateTemp(dir, fmt.Sprintf("%020d-*.json", time.Now().UnixNano()))
+	if err != nil {
+		return fmt.Errorf("error creating build info edits file: %w", err)
+	}
+	defer file.Close()
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(delta); err != nil {
+		return fmt.Errorf("error writing build info edits to %s: %w", file.Name(), err)
+	}
+	logrus.Infof("Saved the build info edits to %s for the publishing step", file.Name())
+	return nil
+}
+
+// loadBuildInfoEdits returns the edits saved by the steps that collected the
+// build, in the order they were saved.
+func loadBuildInfoEdits(args Args) ([]buildInfoEdit, error) {
+	dir := buildInfoEditsDir(args)
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading build info edits: %w", err)
+	}
+	var edits []buildInfoEdit
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("error reading build info edits: %w", err)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(content))
+		decoder.UseNumber()
+		var delta buildInfoDelta
+		if err := decoder.Decode(&delta); err != nil {
+			return nil, fmt.Errorf("error parsing build info edits %s: %w", entry.Name(), err)
+		}
+		edits = append(edits, applyBuildInfoDelta(delta))
+	}
+	logrus.Infof("Applying the build info edits of %d step(s)", len(edits))
+	return edits, nil
+}
+
+// diffBuildInfo returns what changed from before to after. Modules are
+// compared by ID, the other fields with a merge patch.
+func diffBuildInfo(before, after map[string]interface{}) buildInfoDelta {
+	beforeModules, afterModules := modulesByID(before), modulesByID(after)
+	var delta buildInfoDelta
+	for _, id := range moduleIDs(before) {
+		if _, ok := afterModules[id]; !ok {
+			delta.RemovedModules = append(delta.RemovedModules, id)
+		}
+	}
+	for _, id := range moduleIDs(after) {
+		if !reflect.DeepEqual(beforeModules[id], afterModules[id]) {
+			delta.Modules = append(delta.Modules, afterModules[id])
+		}
+	}
+	delta.Patch = createMergePatch(withoutModules(before), withoutModules(after))
+	return delta
+}
+
+// applyBuildInfoDelta returns an edit that makes the changes of the delta.
+func applyBuildInfoDelta(delta buildInfoDelta) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		edited := map[string]map[string]interface{}{}
+		for _, module := range delta.Modules {
+			id, _ := module["id"].(string)
+			edited[id] = module
+		}
+		modules, _ := buildInfo["modules"].([]interface{})
+		var kept []interface{}
+		for _, m := range modules {
+			module, _ := m.(map[string]interface{})
+			id, _ := module["id"].(string)
+			if slices.Contains(delta.RemovedModules, id) {
+				continue
+			}
+			if replacement, ok := edited[id]; ok {
+				kept = append(kept, replacement)
+				delete(edited, id)
+				continue
+			}
+			kept = append(kept, m)
+		}
+		for _, module := range delta.Modules {
+			id, _ := module["id"].(string)
+			if _, ok := edited[id]; ok {
+				kept = append(kept, module)
+			}
+		}
+		buildInfo["modules"] = kept
+		mergeObject(buildInfo, delta.Patch)
+		return nil
+	}
+}
+
+// modulesByID returns the modules of the build info by ID.
+func modulesByID(buildInfo map[string]interface{}) map[string]map[string]interface{} {
+	byID := map[string]map[string]interface{}{}
+	modules, _ := buildInfo["modules"].([]interface{})
+	for _, m := range modules {
+		if module, ok := m.(map[string]interface{}); ok {
+			id, _ := module["id"].(string)
+			byID[id] = module
+		}
+	}
+	return byID
+}
+
+// moduleIDs returns the IDs of the modules of the build info, in order.
+func moduleIDs(buildInfo map[string]interface{}) []string {
+	var ids []string
+	modules, _ := buildInfo["modules"].([]interface{})
+	for _, m := range modules {
+		if module, ok := m.(map[string]interface{}); ok {
+			id, _ := module["id"].(string)
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// withoutModules returns the fields of the build info other than the modules.
+func withoutModules(buildInfo map[string]interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for key, value := range buildInfo {
+		if key != "modules" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildInfoDelta(t *testing.T) {
+	assembled := func(moduleIDs ...string) map[string]interface{} {
+		var modules []interface{}
+		for _, id := range moduleIDs {
+			modules = append(modules, map[string]interface{}{"id": id, "type": "docker"})
+		}
+		return map[string]interface{}{
+			"name":       "app",
+			"number":     "1",
+			"properties": map[string]interface{}{"buildInfo.env.CI": "true"},
+			"modules":    modules,
+		}
+	}
+
+	// The collecting step sees its own module
+	before := assembled("app:1.0", "old:1.0")
+	after := assembled("app:1.0", "old:1.0")
+	edits := []buildInfoEdit{
+		addModuleProperties("app:1.0", map[string]string{"service": "app"}),
+		renameModule("old:1.0", "renamed"),
+		addBuildProperties(map[string]string{"docker.image.size": "3000"}),
+		setBuildInfoField("principal", "svc"),
+	}
+	for _, edit := range edits {
+		if err := edit(after); err != nil {
+			t.Fatal(err)
+		}
+	}
+	delta := diffBuildInfo(before, after)
+
+	// The publishing step also sees the module of another step
+	published := assembled("app:1.0", "old:1.0", "other:2.0")
+	if err := applyBuildInfoDelta(delta)(published); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"name":       "app",
+		"number":     "1",
+		"principal":  "svc",
+		"properties": map[string]interface{}{"buildInfo.env.CI": "true", "docker.image.size": "3000"},
+		"modules": []interface{}{
+			map[string]interface{}{"id": "app:1.0", "type": "docker", "properties": map[string]interface{}{"service": "app"}},
+			map[string]interface{}{"id": "other:2.0", "type": "docker"},
+			map[string]interface{}{"id": "renamed", "type": "docker"},
+		},
+	}
+	if !reflect.DeepEqual(published, want) {
+		t.Errorf("published build info = %v, want %v", published, want)
+	}
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	before := map[string]interface{}{
+		"keep":    "same",
+		"change":  "old",
+		"remove":  "gone",
+		"object":  map[string]interface{}{"a": "1", "b": "2"},
+		"replace": []interface{}{"x"},
+	}
+	after := map[string]interface{}{
+		"keep":    "same",
+		"change":  "new",
+		"add":     "added",
+		"object":  map[string]interface{}{"a": "1", "c": "3"},
+		"replace": []interface{}{"x", "y"},
+	}
+	patch := createMergePatch(before, after)
+	want := map[string]interface{}{
+		"change":  "new",
+		"remove":  nil,
+		"add":     "added",
+		"object":  map[string]interface{}{"b": nil, "c": "3"},
+		"replace": []interface{}{"x", "y"},
+	}
+	if !reflect.DeepEqual(patch, want) {
+		t.Errorf("createMergePatch() = %v, want %v", patch, want)
+	}
+
+	// Applying the patch gives the new value
+	mergeObject(before, patch)
+	if !reflect.DeepEqual(before, after) {
+		t.Errorf("merged = %v, want %v", before, after)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces secrets in logged text, as in the redacted env file values.
+const redacted = "***"
+
+var (
+	// jwtPattern matches JSON web tokens, the format of Artifactory access tokens.
+	jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)
+	// authSchemePattern matches credentials following an authorization scheme.
+	authSchemePattern = regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`)
+	// secretFieldPattern matches the values of JSON fields named like secrets.
+	secretFieldPattern = regexp.MustCompile(`(?i)("[a-z_-]*(?:password|passwd|secret|token|apikey|api_key|credential|private_key)[a-z_-]*"\s*:\s*")[^"]*"`)
+	// signedURLPattern matches the signature and token parameters of signed
+	// URLs, which proxies and cloud storage redirects echo in errors.
+	signedURLPattern = regexp.MustCompile(`(?i)([?&](?:x-amz-signature|x-amz-credential|x-amz-security-token|signature|sig|token|access_token)=)[^&\s"'<>]+`)
+)
+
+// redactResponseBody removes the credentials of the request, tokens and URL
+// signatures from a response body before it ends up in an error or the log.
+func redactResponseBody(req *http.Request, body string) string {
+	for _, secret := range requestSecrets(req) {
+		body = strings.ReplaceAll(body, secret, redacted)
+	}
+	body = jwtPattern.ReplaceAllString(body, redacted)
+	body = authSchemePattern.ReplaceAllString(body, "$1 "+redacted)
+	body = secretFieldPattern.ReplaceAllString(body, `${1}`+redacted+`"`)
+	return signedURLPattern.ReplaceAllString(body, "${1}"+redacted)
+}
+
+// requestSecrets returns the credentials the request was sent with.
+func requestSecrets(req *http.Request) []string {
+	var secrets []string
+	if _, password, ok := req.BasicAuth(); ok && password != "" {
+		secrets = append(secrets, password)
+	}
+	if apiKey := req.Header.Get("X-JFrog-Art-Api"); apiKey != "" {
+		secrets = append(secrets, apiKey)
+	}
+	if token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+		secrets = append(secrets, token)
+	}
+	return secrets
+}
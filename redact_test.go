@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRedactResponseBody(t *testing.T) {
+	basic, _ := http.NewRequest(http.MethodGet, "https://acme.jfrog.io/artifactory/api/system/version", nil)
+	basic.SetBasicAuth("ci", "hunter2")
+	bearer, _ := http.NewRequest(http.MethodGet, "https://acme.jfrog.io/artifactory/api/system/version", nil)
+	bearer.Header.Set("Authorization", "Bearer opaque-token-value")
+	apiKey, _ := http.NewRequest(http.MethodGet, "https://acme.jfrog.io/artifactory/api/system/version", nil)
+	apiKey.Header.Set("X-JFrog-Art-Api", "AKCp8key")
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		body string
+		want string
+	}{
+		{"password", basic, "bad credentials for ci:hunter2", "bad credentials for ci:***"},
+		{"token", bearer, "token opaque-token-value expired", "token *** expired"},
+		{"api key", apiKey, "api key AKCp8key is revoked", "api key *** is revoked"},
+		{"jwt", basic, "invalid token eyJhbGciOiJSUzI1NiJ9.eyJzdWIiOiJjaSJ9.c2ln", "invalid token ***"},
+		{"auth scheme", basic, "sent Authorization: Bearer abc.def", "sent Authorization: Bearer ***"},
+		{"json field", basic, `{"user":"ci","password":"p4ss","api_key":"k3y"}`, `{"user":"ci","password":"***","api_key":"***"}`},
+		{"signed url", basic, "redirect to https://s3.example.com/blob?X-Amz-Signature=abc123&X-Amz-Date=1", "redirect to https://s3.example.com/blob?X-Amz-Signature=***&X-Amz-Date=1"},
+		{"nothing to redact", basic, `{"errors":[{"status":404,"message":"Not Found"}]}`, `{"errors":[{"status":404,"message":"Not Found"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redactResponseBody(tt.req, tt.body); got != tt.want {
+				t.Errorf("redactResponseBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// repositoryConfig is the part of a repository configuration the plugin uses.
+type repositoryConfig struct {
+	PackageType string `json:"packageType"`
+	RClass      string `json:"rclass"`
+	URL         string `json:"url"`
+}
+
+// checkImageRepository verifies that the repository exists and holds Docker or
+// OCI images, so a mistyped repository fails with a clear error instead of an
+// empty search result, and returns its configuration.
+func checkImageRepository(ctx context.Context, args Args, sanitizedURL, repo string) (repositoryConfig, error) {
+	var config repositoryConfig
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+"api/repositories/"+url.PathEscape(repo), nil, args)
+	if err != nil {
+		return config, err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		var httpErr *httpError
+		if errors.As(err, &httpErr) && httpErr.StatusCode < 500 {
+			return config, fmt.Errorf("repository %s does not exist or the user cannot read it", repo)
+		}
+		return config, fmt.Errorf("error checking repository %s: %w", repo, err)
+	}
+
+	if err := json.Unmarshal(body, &config); err != nil {
+		return config, fmt.Errorf("error parsing repository %s configuration: %w", repo, err)
+	}
+	// Older servers leave the package type out of the configuration
+	switch strings.ToLower(config.PackageType) {
+	case "", "docker", "oci":
+		return config, nil
+	}
+	return config, fmt.Errorf("repository %s is a %s repository, not a Docker or OCI repository", repo, config.PackageType)
+}
+
+// useRemoteCache points the image at the cache of the remote repository it
+// was pulled or pushed through, where Artifactory stores its manifest. Docker
+// Hub keeps official images under library/, which the cache path includes.
+func useRemoteCache(img *dockerImage, config repositoryConfig) {
+	cacheRepo := img.Repo + "-cache"
+	if strings.Contains(config.URL, "docker.io") && !strings.Contains(img.Name, "/") {
+		img.Name = "library/" + img.Name
+	}
+	logrus.Infof("Repository %s is a remote repository, searching %s in its cache %s", img.Repo, img.Name, cacheRepo)
+	img.Repo = cacheRepo
+}
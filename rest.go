@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// httpClient is shared by all HTTP calls the plugin makes, so connections and
+// TLS sessions are reused across requests. Images are processed concurrently,
+// so more idle connections are kept per host than the default two. The
+// timeouts are set from PLUGIN_HTTP_TIMEOUT, PLUGIN_DIAL_TIMEOUT and
+// PLUGIN_TLS_TIMEOUT once the settings are read.
+var httpClient = &http.Client{
+	Timeout:   60 * time.Second,
+	Transport: newTransport(30*time.Second, 10*time.Second),
+}
+
+// newTransport returns the default transport with limits suited to a run that
+// sends bursts of requests to one Artifactory instance, giving up on opening a
+// connection after dialTimeout and on the TLS handshake after tlsTimeout.
+func newTransport(dialTimeout, tlsTimeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConns = 32
+	transport.MaxIdleConnsPerHost = 16
+	transport.MaxConnsPerHost = 32
+	transport.IdleConnTimeout = 90 * time.Second
+	transport.TLSHandshakeTimeout = tlsTimeout
+	return transport
+}
+
+// httpError is returned for REST responses with a non-2xx status code.
+type httpError struct {
+	StatusCode int
+	Status     string
+	Body       string
+	// RetryAfter is the wait the server asked for before a retry, if any
+	RetryAfter time.Duration
+}
+
+func (e *httpError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected response status %s", e.Status)
+	}
+	return fmt.Sprintf("unexpected response status %s: %s", e.Status, e.Body)
+}
+
+// newRequest creates a request against the Artifactory REST API, identified by
+// the plugin's User-Agent, with the PLUGIN_HTTP_HEADERS headers, for example for a
+// proxy in front of Artifactory, and authenticated with the configured
+// credentials.
+func newRequest(ctx context.Context, method, requestURL string, body io.Reader, args Args) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", serviceName+"/"+version)
+	for key, value := range parseKeyValueList(args.HTTPHeaders) {
+		req.Header.Set(key, value)
+	}
+	setAuthHeader(req, args)
+	return req, nil
+}
+
+// setAuthHeader sets the authentication header for the request based on the provided args,
+// using the same precedence as setAuthParams.
+func setAuthHeader(req *http.Request, args Args) {
+	if args.Username != "" && args.Password != "" {
+		req.SetBasicAuth(args.Username, args.Password)
+	} else if args.APIKey != "" {
+		req.Header.Set("X-JFrog-Art-Api", args.APIKey)
+	} else if args.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+args.AccessToken)
+	}
+}
+
+// doRequest sends the request and returns the response body, or an *httpError
+// when the response status is not 2xx. A request that fails with a transient
+// error, such as a rate limit, is sent again as withRetries does for the jfrog
+// CLI commands, so its body must be replayable through req.GetBody.
+func doRequest(req *http.Request, args Args) ([]byte, error) {
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return sendRequest(req)
+	}
+	var body []byte
+	attempt := req
+	err := withRetries(req.Context(), args, req.Method+" "+req.URL.Path, func() error {
+		var err error
+		body, err = sendRequest(attempt)
+		if err != nil && req.GetBody != nil {
+			// The failed attempt consumed the request body
+			attempt = req.Clone(req.Context())
+			var bodyErr error
+			if attempt.Body, bodyErr = req.GetBody(); bodyErr != nil {
+				return fmt.Errorf("%v, and the request body cannot be sent again: %w", err, bodyErr)
+			}
+		}
+		return err
+	})
+	return body, err
+}
+
+// sendRequest sends the request once and returns the response body, or an
+// *httpError when the response status is not 2xx.
+func sendRequest(req *http.Request) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		// Error bodies are logged, and may echo credentials or signed URLs
+		return body, &httpError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       redactResponseBody(req, string(body)),
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		}
+	}
+	return body, nil
+}
+
+// checksums are the checksums of an item in Artifactory.
+type checksums struct {
+	Sha1   string `json:"sha1"`
+	Sha256 string `json:"sha256"`
+	Md5    string `json:"md5"`
+}
+
+// getChecksums returns the checksums of an item from the storage API.
+func getChecksums(ctx context.Context, args Args, sanitizedURL, itemPath string) (checksums, error) {
+	var info struct {
+		Checksums checksums `json:"checksums"`
+	}
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+"api/storage/"+escapeItemPath(itemPath), nil, args)
+	if err != nil {
+		return info.Checksums, err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return info.Checksums, err
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return info.Checksums, fmt.Errorf("error parsing storage info: %w", err)
+	}
+	return info.Checksums, nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// transientOutput matches the HTTP status phrases in the jfrog CLI output of
+// requests that failed with a gateway or availability error or were rate
+// limited, and the errors of connections dropped by the server, which usually
+// succeed when repeated. Bare numbers are not matched, as they also appear in
+// sizes, digests and build numbers.
+var transientOutput = regexp.MustCompile(`\b(Too Many Requests|Bad Gateway|Service Unavailable|Gateway Timeout|connection reset by peer|EOF)\b`)
+
+// retryCounts counts the retries of each retried action of the run, such as a
+// jfrog CLI command, for the pushed metrics.
+var retryCounts = struct {
+	sync.Mutex
+	byAction map[string]int
+}{byAction: map[string]int{}}
+
+// countRetries adds n retries to the count of the action.
+func countRetries(action string, n int) {
+	retryCounts.Lock()
+	defer retryCounts.Unlock()
+	retryCounts.byAction[action] += n
+}
+
+// transientError is a failure worth retrying.
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+
+func (e *transientError) Unwrap() error { return e.err }
+
+// isTransient reports whether err is a gateway or availability error, a rate
+// limit or a connection dropped by the server, from either the jfrog CLI or the
+// REST API.
+func isTransient(err error) bool {
+	var transientErr *transientError
+	if errors.As(err, &transientErr) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	var httpErr *httpError
+	if errors.As(err, &httpErr) {
+		switch httpErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	return false
+}
+
+// withRetries runs fn and repeats it with exponential backoff while it fails
+// with a transient error, at most PLUGIN_PUBLISH_RETRIES more times. The wait
+// starts at PLUGIN_RETRY_DELAY and is jittered, so that parallel steps do not
+// retry in lockstep. A longer wait asked for by the Retry-After header of the
+// response is respected. The wait ends early when ctx is done.
+func withRetries(ctx context.Context, args Args, action string, fn func() error) error {
+	delay := time.Duration(args.RetryDelay) * time.Second
+	countRetries(action, 0)
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > args.PublishRetries || !isTransient(err) {
+			return err
+		}
+		wait := jitter(delay)
+		var httpErr *httpError
+		if errors.As(err, &httpErr) && httpErr.RetryAfter > wait {
+			wait = httpErr.RetryAfter
+		}
+		logrus.Warnf("%s failed with a transient error, retrying in %s (%d/%d): %v", action, wait, attempt, args.PublishRetries, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+		countRetries(action, 1)
+		delay *= 2
+	}
+}
+
+// jitter returns a random wait between half of delay and delay.
+func jitter(delay time.Duration) time.Duration {
+	if delay <= 1 {
+		return delay
+	}
+	return delay/2 + rand.N(delay/2)
+}
+
+// parseRetryAfter returns the wait asked for by a Retry-After header, given
+// either in seconds or as an HTTP date, or 0 when there is none.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// runCommandWithRetries runs a jfrog CLI command, repeating it while it fails
+// with a transient server error.
+func runCommandWithRetries(ctx context.Context, args Args, cmdArgs []string) error {
+	return withRetries(ctx, args, strings.Join(cmdArgs[:3], " "), func() error {
+		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		output, err := cmd.CombinedOutput()
+		logrus.Infof("Command output:\n%s\n", string(output))
+		if err != nil {
+			logrus.Errorf("Error executing command: %v", err)
+			if transientOutput.Match(output) {
+				return &transientError{commandFailure(err, output)}
+			}
+			return commandFailure(err, output)
+		}
+		return nil
+	})
+}
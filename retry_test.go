@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"rate limited", &httpError{StatusCode: http.StatusTooManyRequests}, true},
+		{"bad gateway", &httpError{StatusCode: http.StatusBadGateway}, true},
+		{"not found", &httpError{StatusCode: http.StatusNotFound}, false},
+		{"wrapped unavailable", fmt.Errorf("error deploying build info: %w", &httpError{StatusCode: http.StatusServiceUnavailable}), true},
+		{"EOF", fmt.Errorf("Get \"https://acme.jfrog.io\": %w", io.EOF), true},
+		{"unexpected EOF", io.ErrUnexpectedEOF, true},
+		{"connection reset", fmt.Errorf("read tcp: %w", syscall.ECONNRESET), true},
+		{"connection refused", fmt.Errorf("dial tcp: %w", syscall.ECONNREFUSED), false},
+		{"command output", &transientError{errors.New("Service Unavailable")}, true},
+		{"other", errors.New("invalid build name"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("%s: isTransient(%v) = %v, want %v", tt.name, tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestJitter(t *testing.T) {
+	const delay = 2 * time.Second
+	for i := 0; i < 100; i++ {
+		if got := jitter(delay); got < delay/2 || got >= delay {
+			t.Fatalf("jitter(%s) = %s, want between %s and %s", delay, got, delay/2, delay)
+		}
+	}
+	if got := jitter(0); got != 0 {
+		t.Errorf("jitter(0) = %s, want 0", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"0", 0},
+		{"-5", 0},
+		{"soon", 0},
+		{"Wed, 01 May 2024 10:01:00 GMT", time.Minute},
+		{"Wed, 01 May 2024 09:59:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
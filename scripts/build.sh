@@ -3,12 +3,25 @@
 # force go modules
 export GOPATH=""
 
-# disable cgo
+# disable cgo, except for FIPS builds where BoringCrypto is linked in with cgo
 export CGO_ENABLED=0
+if [ "${FIPS}" = "true" ]; then
+	export CGO_ENABLED=1
+	export GOEXPERIMENT=boringcrypto
+fi
 
 set -e
 set -x
 
-# linux
-GOOS=linux GOARCH=amd64 go build -o release/linux/amd64/plugin
-GOOS=linux GOARCH=arm64 go build -o release/linux/arm64/plugin
+# embed the version and commit
+VERSION=${DRONE_TAG:-dev}
+COMMIT=${DRONE_COMMIT_SHA:-$(git rev-parse --short HEAD 2>/dev/null || echo unknown)}
+LDFLAGS="-X main.version=${VERSION} -X main.commit=${COMMIT}"
+
+# linux, FIPS builds only for the host architecture since cgo does not cross-compile
+if [ "${FIPS}" = "true" ]; then
+	go build -ldflags "${LDFLAGS}" -o release/linux/$(go env GOARCH)/plugin
+	exit 0
+fi
+GOOS=linux GOARCH=amd64 go build -ldflags "${LDFLAGS}" -o release/linux/amd64/plugin
+GOOS=linux GOARCH=arm64 go build -ldflags "${LDFLAGS}" -o release/linux/arm64/plugin
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadSettingsFile reads plugin settings from a YAML or JSON file and exports
+// each one as the PLUGIN_* variable Drone would set for it. Variables already
+// set in the environment take precedence over the file. Values are encoded the
+// way Drone encodes settings: lists are comma separated and objects are JSON.
+func loadSettingsFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading settings file: %w", err)
+	}
+
+	// YAML is a superset of JSON, so one decoder handles both formats
+	var settings map[string]interface{}
+	if err := yaml.Unmarshal(content, &settings); err != nil {
+		return fmt.Errorf("error parsing settings file %s: %w", path, err)
+	}
+
+	for key, value := range settings {
+		env := "PLUGIN_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if _, ok := os.LookupEnv(env); ok {
+			continue
+		}
+		encoded, err := encodeSetting(value)
+		if err != nil {
+			return fmt.Errorf("error encoding setting %s: %w", key, err)
+		}
+		if err := os.Setenv(env, encoded); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeSetting converts a decoded setting value to its environment variable form.
+func encodeSetting(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case time.Time:
+		// Unquoted dates are decoded as timestamps
+		return v.Format(time.RFC3339), nil
+	case []interface{}:
+		// Lists of scalars are comma separated, lists containing objects are JSON
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			switch item.(type) {
+			case map[string]interface{}, []interface{}:
+				return marshalSetting(v)
+			}
+			encoded, err := encodeSetting(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, encoded)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return marshalSetting(v)
+	}
+}
+
+func marshalSetting(value interface{}) (string, error) {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
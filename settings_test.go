@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEncodeSetting(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"null", nil, ""},
+		{"string", "docker-local", "docker-local"},
+		{"bool", true, "true"},
+		{"int", 3, "3"},
+		{"float", 1.5, "1.5"},
+		{"whole float", float64(60), "60"},
+		{"timestamp", time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC), "2024-05-01T10:00:00Z"},
+		{"list", []interface{}{"app:1.0", "app:latest"}, "app:1.0,app:latest"},
+		{"mixed list", []interface{}{"a", 1, false}, "a,1,false"},
+		{"empty list", []interface{}{}, ""},
+		{"list of objects", []interface{}{map[string]interface{}{"url": "https://x.io"}}, `[{"url":"https://x.io"}]`},
+		{"nested list", []interface{}{[]interface{}{"a"}}, `[["a"]]`},
+		{"object", map[string]interface{}{"team": "core"}, `{"team":"core"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := encodeSetting(tt.value)
+			if err != nil {
+				t.Fatalf("encodeSetting(%v) error = %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("encodeSetting(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// notationSignatureType is the artifact type of notation signatures.
+const notationSignatureType = "application/vnd.cncf.notary.signature"
+
+// fetchReferrers returns the artifacts that refer to the image manifest, such
+// as signatures and attestations, from the OCI referrers API of the repository.
+func fetchReferrers(ctx context.Context, args Args, sanitizedURL string, img dockerImage) ([]manifestDescriptor, error) {
+	referrersPath := "api/docker/" + escapeItemPath(img.Repo+"/v2/"+img.Name+"/referrers/"+img.digest())
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+referrersPath, nil, args)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return nil, fmt.Errorf("error listing the referrers of %s: %w", img.Ref, err)
+	}
+
+	var index imageManifest
+	if err := json.Unmarshal(body, &index); err != nil {
+		return nil, fmt.Errorf("error parsing the referrers of %s: %w", img.Ref, err)
+	}
+	return index.Manifests, nil
+}
+
+// notationSignatureProperties returns the digests and references of the
+// notation signatures of the image as properties, or nil when it is unsigned.
+func notationSignatureProperties(img dockerImage, referrers []manifestDescriptor) map[string]string {
+	var digests, references []string
+	for _, referrer := range referrers {
+		if referrer.ArtifactType != notationSignatureType {
+			continue
+		}
+		digests = append(digests, referrer.Digest)
+		references = append(references, img.Repo+"/"+img.Name+"@"+referrer.Digest)
+	}
+	if len(digests) == 0 {
+		logrus.Infof("No notation signature found for %s", img.Ref)
+		return nil
+	}
+	logrus.Infof("Recording %d notation signature(s) of %s", len(digests), img.Ref)
+	return map[string]string{
+		"notation.signature.digest":    strings.Join(digests, ","),
+		"notation.signature.reference": strings.Join(references, ","),
+	}
+}
+
+// cosignArtifacts returns build-info artifacts for the cosign signature and
+// attestation of the image, which cosign pushes as the sha256-<hex>.sig and
+// sha256-<hex>.att tags next to the image.
+func cosignArtifacts(ctx context.Context, args Args, sanitizedURL string, img dockerImage) ([]map[string]interface{}, error) {
+	var artifacts []map[string]interface{}
+	for _, suffix := range []string{".sig", ".att"} {
+		tag := strings.Replace(img.digest(), ":", "-", 1) + suffix
+		manifestPath := img.Repo + "/" + img.Name + "/" + tag + "/manifest.json"
+		manifest, err := fetchManifest(ctx, args, sanitizedURL, manifestPath)
+		var httpErr *httpError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			logrus.Infof("No cosign %s tag found for %s", tag, img.Ref)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		logrus.Infof("Recording the cosign %s tag of %s", tag, img.Ref)
+		tagArtifacts, err := manifestArtifacts(ctx, args, sanitizedURL, manifestPath, manifest)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, tagArtifacts...)
+	}
+	return artifacts, nil
+}
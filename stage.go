@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// stageImage copies or moves the folder of the image tag to the same path in
+// the target repository. Artifactory keeps the properties of copied and moved
+// items, so the image stays linked to its build.
+func stageImage(ctx context.Context, args Args, sanitizedURL, imageDir string) error {
+	action := firstNonEmpty(args.TargetAction, "copy")
+	_, itemPath, _ := strings.Cut(imageDir, "/")
+	target := args.TargetRepo + "/" + itemPath
+	logrus.Infof("Running %s of %s to %s", action, imageDir, target)
+
+	query := url.Values{"to": {"/" + target}, "failFast": {"1"}}
+	requestURL := sanitizedURL + "api/" + action + "/" + escapeItemPath(imageDir) + "?" + query.Encode()
+	req, err := newRequest(ctx, http.MethodPost, requestURL, nil, args)
+	if err != nil {
+		return err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return fmt.Errorf("error running %s of %s to %s: %w", action, imageDir, target, err)
+	}
+
+	var result struct {
+		Messages []struct {
+			Level   string `json:"level"`
+			Message string `json:"message"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil {
+		for _, m := range result.Messages {
+			logrus.Infof("%s: %s", m.Level, m.Message)
+		}
+	}
+	return nil
+}
+
+// promoteImage promotes the image to the promotion target repository with
+// jfrog rt docker-promote, optionally renaming it and retagging it.
+func promoteImage(args Args, sanitizedURL, repo, imageName, imageTag string) error {
+	logrus.Infof("Promoting %s:%s to %s", imageName, imageTag, args.PromoteTargetRepo)
+	cmdArgs := []string{"jfrog", "rt", "docker-promote", imageName, repo, args.PromoteTargetRepo, "--source-tag=" + imageTag, "--url=" + sanitizedURL}
+	if args.PromoteTargetImage != "" {
+		cmdArgs = append(cmdArgs, "--target-docker-image="+args.PromoteTargetImage)
+	}
+	if args.PromoteTargetTag != "" {
+		cmdArgs = append(cmdArgs, "--target-tag="+args.PromoteTargetTag)
+	}
+	if args.PromoteCopy {
+		cmdArgs = append(cmdArgs, "--copy")
+	}
+	cmdArgs, err := setAuthParams(cmdArgs, args)
+	if err != nil {
+		logrus.Errorf("error setting auth parameters: %v", err)
+	}
+	if err := runCommand(cmdArgs); err != nil {
+		return fmt.Errorf("error executing jfrog rt docker-promote command: %w", err)
+	}
+	return nil
+}
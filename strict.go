@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+)
+
+// applyStrictMode makes the checks that default to warnings fail the step
+// instead, unless their severity is set explicitly.
+func applyStrictMode(args *Args) {
+	if !args.Strict {
+		return
+	}
+	args.RequireGit = true
+	args.PrincipalFailure = firstNonEmpty(args.PrincipalFailure, "error")
+}
+
+// warnOrFail logs a problem that leaves the build info incomplete as a
+// warning, or returns it as an error in strict mode.
+func warnOrFail(args Args, format string, a ...interface{}) error {
+	if args.Strict {
+		return fmt.Errorf(format, a...)
+	}
+	logrus.Warnf(format, a...)
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// warningCollector is a logrus hook that keeps the warnings logged during the
+// run so they can be repeated in the step summary.
+type warningCollector struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (c *warningCollector) Levels() []logrus.Level {
+	return []logrus.Level{logrus.WarnLevel}
+}
+
+func (c *warningCollector) Fire(entry *logrus.Entry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, entry.Message)
+	return nil
+}
+
+func (c *warningCollector) warnings() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.messages...)
+}
+
+// summaryFile returns the file the markdown step summary is written to.
+func summaryFile(args Args) string {
+	return firstNonEmpty(args.SummaryFile, os.Getenv("GITHUB_STEP_SUMMARY"))
+}
+
+// writeSummary appends a markdown summary of the run to the summary file.
+func writeSummary(file string, summary publishSummary, warnings []string, runErr error) error {
+	var b strings.Builder
+	if runErr != nil {
+		fmt.Fprintf(&b, "## :x: Build info for %s #%s failed\n\n", summary.BuildName, summary.Number)
+		fmt.Fprintf(&b, "```\n%s\n```\n\n", runErr)
+	} else {
+		fmt.Fprintf(&b, "## :white_check_mark: Build info published for %s #%s\n\n", summary.BuildName, summary.Number)
+	}
+
+	build := summary.BuildName + " #" + summary.Number
+	if summary.Link != "" {
+		build = fmt.Sprintf("[%s](%s)", build, summary.Link)
+	}
+	b.WriteString("| Image | Digest | Build |\n| --- | --- | --- |\n")
+	for _, img := range summary.Images {
+		digest := "-"
+		if img.digest() != "" {
+			digest = "`" + img.digest() + "`"
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | %s |\n", img.Ref, digest, markdownEscape(build))
+	}
+
+	if len(warnings) > 0 {
+		b.WriteString("\n### Warnings\n\n")
+		for _, w := range warnings {
+			fmt.Fprintf(&b, "- %s\n", strings.ReplaceAll(w, "\n", " "))
+		}
+	}
+	b.WriteString("\n")
+
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening summary file: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(b.String()); err != nil {
+		return fmt.Errorf("error writing summary file: %w", err)
+	}
+	return nil
+}
+
+// markdownEscape escapes the characters that end a markdown table cell.
+func markdownEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// errManifestNotFound is returned when the search finds no manifest for an image.
+var errManifestNotFound = errors.New("no manifest found")
+
+// maxNearbyTags is the number of existing tags suggested when a tag is not found.
+const maxNearbyTags = 5
+
+// tagNotFoundError explains that no manifest was found for the image, listing
+// the existing tags of the image closest to the requested one, to tell a
+// mistyped tag from an image that was not pushed yet.
+func tagNotFoundError(ctx context.Context, args Args, sanitizedURL string, img dockerImage) error {
+	tags, err := listTags(ctx, args, sanitizedURL, img)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%w for %s:%s in repository %s", errManifestNotFound, img.Name, img.Tag, img.Repo)
+	case len(tags) == 0:
+		return fmt.Errorf("%w for %s:%s in repository %s, the image has no tags there; was it pushed to this repository?", errManifestNotFound, img.Name, img.Tag, img.Repo)
+	}
+	return fmt.Errorf("%w for %s:%s in repository %s, closest existing tags: %s", errManifestNotFound, img.Name, img.Tag, img.Repo, strings.Join(nearbyTags(img.Tag, tags), ", "))
+}
+
+// expandTagPattern replaces each image by one image for every tag of it in the
+// repository that matches the glob pattern, so a family of tags pushed
+// together is recorded in one build.
+func expandTagPattern(ctx context.Context, args Args, sanitizedURL string, images []dockerImage, pattern string) ([]dockerImage, error) {
+	var expanded []dockerImage
+	for _, img := range images {
+		tags, err := listTags(ctx, args, sanitizedURL, img)
+		if err != nil {
+			return nil, fmt.Errorf("error listing tags of %s in repository %s: %w", img.Name, img.Repo, err)
+		}
+		sort.Strings(tags)
+		base := strings.TrimSuffix(img.Ref, ":"+img.Tag)
+		matched := 0
+		for _, tag := range tags {
+			if ok, _ := path.Match(pattern, tag); !ok {
+				continue
+			}
+			matched++
+			match := img
+			match.Ref, match.Tag = base+":"+tag, tag
+			expanded = append(expanded, match)
+		}
+		if matched == 0 {
+			return nil, fmt.Errorf("%w for %s in repository %s, no tag matches %q", errManifestNotFound, img.Name, img.Repo, pattern)
+		}
+		logrus.Infof("Tag pattern %q matched %d tags of %s", pattern, matched, img.Name)
+	}
+	return expanded, nil
+}
+
+// listTags returns the tags of the image from the Docker registry API of the repository.
+func listTags(ctx context.Context, args Args, sanitizedURL string, img dockerImage) ([]string, error) {
+	req, err := newRequest(ctx, http.MethodGet, sanitizedURL+"api/docker/"+escapeItemPath(img.Repo+"/v2/"+img.Name+"/tags/list"), nil, args)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		var httpErr *httpError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var list struct {
+		Tags []string `json:"tags"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("error parsing tags: %w", err)
+	}
+	return list.Tags, nil
+}
+
+// nearbyTags returns the tags closest to tag by edit distance, closest first.
+func nearbyTags(tag string, tags []string) []string {
+	distances := map[string]int{}
+	for _, t := range tags {
+		distances[t] = editDistance(tag, t)
+	}
+	sorted := append([]string(nil), tags...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return distances[sorted[i]] < distances[sorted[j]]
+	})
+	if len(sorted) > maxNearbyTags {
+		sorted = sorted[:maxNearbyTags]
+	}
+	return sorted
+}
+
+// editDistance returns the Levenshtein distance between two strings.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
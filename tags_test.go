@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"1.0", "", 3},
+		{"", "1.0", 3},
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", 1},
+		{"latest", "lates", 1},
+		{"v1.0", "1.0", 1},
+		{"kitten", "sitting", 3},
+		{"1.0.0", "0.0.1", 2},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
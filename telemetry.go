@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const serviceName = "drone-artifactory-docker-buildinfo"
+
+// span is a single timed phase of the plugin run.
+type span struct {
+	name  string
+	id    string
+	start time.Time
+	end   time.Time
+	err   error
+}
+
+// tracer records the phases of a plugin run so they can be exported as
+// OpenTelemetry spans under a single root span.
+type tracer struct {
+	traceID string
+	root    span
+	spans   []span
+}
+
+// newTracer starts a trace whose root span has the given name.
+func newTracer(name string) *tracer {
+	return &tracer{
+		traceID: randomHex(16),
+		root:    span{name: name, id: randomHex(8), start: time.Now()},
+	}
+}
+
+// phase runs fn and records its duration and result as a child span.
+func (t *tracer) phase(name string, fn func() error) error {
+	s := span{name: name, id: randomHex(8), start: time.Now()}
+	err := fn()
+	s.end = time.Now()
+	s.err = err
+	t.spans = append(t.spans, s)
+	if err != nil {
+		return &phaseError{phase: name, err: err}
+	}
+	return err
+}
+
+// summary returns a table of the duration and status of each phase followed by
+// the total duration of the run.
+func (t *tracer) summary() string {
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PHASE\tDURATION\tSTATUS")
+	for _, s := range t.spans {
+		status := "ok"
+		if s.err != nil {
+			status = "failed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.name, s.end.Sub(s.start).Round(time.Millisecond), status)
+	}
+	fmt.Fprintf(w, "total\t%s\t\n", time.Since(t.root.start).Round(time.Millisecond))
+	w.Flush()
+	return buf.String()
+}
+
+// export sends the recorded spans to the configured OTLP/HTTP endpoint. It is a
+// no-op when no endpoint is configured.
+func (t *tracer) export(ctx context.Context, args Args, runErr error) error {
+	endpoint := args.OtelEndpoint
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return nil
+	}
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+
+	headers := args.OtelHeaders
+	if headers == "" {
+		headers = os.Getenv("OTEL_EXPORTER_OTLP_HEADERS")
+	}
+
+	t.root.end = time.Now()
+	t.root.err = runErr
+
+	attributes := []otlpAttribute{
+		stringAttribute("build.name", args.BuildName),
+		stringAttribute("build.number", args.BuildNumber),
+		stringAttribute("docker.image", args.DockerImage),
+	}
+	spans := []otlpSpan{t.otlpSpan(t.root, "", attributes)}
+	for _, s := range t.spans {
+		spans = append(spans, t.otlpSpan(s, t.root.id, attributes))
+	}
+
+	payload := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": []otlpAttribute{stringAttribute("service.name", serviceName)},
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": serviceName},
+						"spans": spans,
+					},
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range parseKeyValueList(headers) {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("collector returned %s", resp.Status)
+	}
+	return nil
+}
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+func (t *tracer) otlpSpan(s span, parentID string, attributes []otlpAttribute) otlpSpan {
+	// Status codes follow the OTLP enum: 1 is OK, 2 is ERROR
+	status := otlpStatus{Code: 1}
+	if s.err != nil {
+		status = otlpStatus{Code: 2, Message: s.err.Error()}
+	}
+	return otlpSpan{
+		TraceID:           t.traceID,
+		SpanID:            s.id,
+		ParentSpanID:      parentID,
+		Name:              s.name,
+		Kind:              1, // SPAN_KIND_INTERNAL
+		StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+		Attributes:        attributes,
+		Status:            status,
+	}
+}
+
+func stringAttribute(key, value string) otlpAttribute {
+	return otlpAttribute{Key: key, Value: map[string]string{"stringValue": value}}
+}
+
+// randomHex returns n random bytes encoded as a hex string.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// parseKeyValueList parses a comma separated list of key=value pairs.
+func parseKeyValueList(list string) map[string]string {
+	values := map[string]string{}
+	for _, pair := range strings.Split(list, ",") {
+		key, value, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			continue
+		}
+		values[key] = strings.TrimSpace(value)
+	}
+	return values
+}
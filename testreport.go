@@ -0,0 +1,29 @@
+package main
+
+import "strings"
+
+// testReportProperties returns build-info properties linking the build to the
+// test evidence of the pipeline: the report URLs and the result counts. Counts
+// that are not set are left out.
+func testReportProperties(args Args) map[string]string {
+	properties := map[string]string{}
+	var urls []string
+	for _, reportURL := range strings.Split(args.TestReportURLs, ",") {
+		if reportURL = strings.TrimSpace(reportURL); reportURL != "" {
+			urls = append(urls, reportURL)
+		}
+	}
+	if len(urls) > 0 {
+		properties["test.report.url"] = strings.Join(urls, ",")
+	}
+	for property, count := range map[string]string{
+		"test.passed":  args.TestsPassed,
+		"test.failed":  args.TestsFailed,
+		"test.skipped": args.TestsSkipped,
+	} {
+		if count != "" {
+			properties[property] = count
+		}
+	}
+	return properties
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validateArgs checks the settings before any work is done and reports every
+// problem found at once, each naming the variable to fix.
+func validateArgs(args Args) error {
+	var errs []error
+	addf := func(format string, a ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, a...))
+	}
+
+	if args.URL == "" {
+		addf("PLUGIN_URL is required")
+	} else if u, err := url.Parse(args.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		addf("PLUGIN_URL %q is not an http(s) URL", args.URL)
+	}
+
+	// Credentials may also come from PLUGIN_CREDENTIALS_DIR or a .netrc file
+	authMethods := 0
+	for _, set := range []bool{args.Password != "", args.APIKey != "", args.AccessToken != ""} {
+		if set {
+			authMethods++
+		}
+	}
+	switch {
+	case authMethods == 0:
+		addf("one of PLUGIN_USERNAME/PLUGIN_PASSWORD, PLUGIN_API_KEY or PLUGIN_ACCESS_TOKEN is required")
+	case authMethods > 1:
+		addf("only one of PLUGIN_PASSWORD, PLUGIN_API_KEY and PLUGIN_ACCESS_TOKEN can be set")
+	case args.Password != "" && args.Username == "":
+		addf("PLUGIN_USERNAME is required with PLUGIN_PASSWORD")
+	}
+
+	if args.Mode != "" && args.Mode != "healthcheck" && args.Mode != "publish" {
+		addf("PLUGIN_MODE %q is not supported, expected healthcheck or publish", args.Mode)
+	}
+	if args.Mode == "healthcheck" {
+		return errors.Join(errs...)
+	}
+
+	// The build partials only outlive the step in a home directory the steps share
+	if (args.SkipPublish || args.Mode == "publish" || args.ParentBuildName != "") && args.CLIHomeDir == "" && os.Getenv("JFROG_CLI_HOME_DIR") == "" {
+		addf("PLUGIN_CLI_HOME_DIR is required with PLUGIN_SKIP_PUBLISH, PLUGIN_PARENT_BUILD_NAME and PLUGIN_MODE publish, set it to the same directory in the shared workspace in all the steps")
+	}
+	if (args.ParentBuildName == "") != (args.ParentBuildNumber == "") {
+		addf("PLUGIN_PARENT_BUILD_NAME and PLUGIN_PARENT_BUILD_NUMBER must be set together")
+	}
+	if args.SkipPublish && args.Mode == "publish" {
+		addf("PLUGIN_SKIP_PUBLISH cannot be used with PLUGIN_MODE publish")
+	}
+	// Publishing a collected build uses the build name and number, the REST
+	// client and the build-info repository as much as collecting it does
+	if args.BuildName == "" {
+		addf("PLUGIN_BUILD_NAME is required when DRONE_REPO_NAME is not set")
+	}
+	if args.BuildNumber == "" {
+		addf("PLUGIN_BUILD_NUMBER is required when DRONE_BUILD_NUMBER is not set")
+	}
+	if args.PublishRetries < 0 {
+		addf("PLUGIN_PUBLISH_RETRIES %d cannot be negative", args.PublishRetries)
+	}
+	if args.RetryDelay < 0 {
+		addf("PLUGIN_RETRY_DELAY %d cannot be negative", args.RetryDelay)
+	}
+	for _, pair := range strings.Split(args.HTTPHeaders, ",") {
+		if key, _, found := strings.Cut(pair, "="); strings.TrimSpace(pair) != "" && (!found || strings.TrimSpace(key) == "" || strings.ContainsAny(strings.TrimSpace(key), " :")) {
+			addf("PLUGIN_HTTP_HEADERS entry %q needs to be a <header>=<value> pair", strings.TrimSpace(pair))
+		}
+	}
+	if args.HTTPTimeout < 1 {
+		addf("PLUGIN_HTTP_TIMEOUT %d needs to be at least 1 second", args.HTTPTimeout)
+	}
+	if args.DialTimeout < 1 {
+		addf("PLUGIN_DIAL_TIMEOUT %d needs to be at least 1 second", args.DialTimeout)
+	}
+	if args.TLSTimeout < 1 {
+		addf("PLUGIN_TLS_TIMEOUT %d needs to be at least 1 second", args.TLSTimeout)
+	}
+
+	checkOneOf := func(env, value string, allowed ...string) {
+		if value == "" {
+			return
+		}
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		addf("%s %q is not supported, expected one of %s", env, value, strings.Join(allowed, ", "))
+	}
+	checkOneOf("PLUGIN_BUILD_NAME_TRANSFORM", args.BuildNameTransform, "replace", "slug")
+	checkOneOf("PLUGIN_API_COMPAT", args.APICompat, "6", "7")
+	if args.BuildInfoRepo != "" && args.BuildInfoRepo != defaultBuildInfoRepo && !strings.HasSuffix(args.BuildInfoRepo, "-build-info") {
+		addf("PLUGIN_BUILD_INFO_REPO %q needs to be named <project>-build-info", args.BuildInfoRepo)
+	}
+	if args.Mode == "publish" {
+		return errors.Join(errs...)
+	}
+
+	if strings.Trim(args.DockerImage, ", ") == "" {
+		addf("PLUGIN_DOCKER_IMAGE or PLUGIN_IMAGE_LIST_FILE is required")
+	}
+	for _, image := range strings.Split(args.DockerImage, ",") {
+		image, digest, _ := strings.Cut(strings.TrimSpace(image), "@")
+		if image == "" {
+			continue
+		}
+		if args.SearchMode == "digest" && !strings.HasPrefix(digest, "sha256:") {
+			addf("PLUGIN_DOCKER_IMAGE %q needs a sha256 digest (<image>:<tag>@sha256:<hex>) with PLUGIN_SEARCH_MODE digest", image)
+		}
+		if strings.HasSuffix(image, ":") {
+			addf("PLUGIN_DOCKER_IMAGE %q has an empty tag", image)
+		} else if !strings.Contains(image, "/") && imageRepoKey(args, image) == "" {
+			addf("PLUGIN_DOCKER_IMAGE %q needs to start with the repository", image)
+		}
+	}
+	if len(manifestNameCriteria(args.ManifestNames)) == 0 {
+		addf("PLUGIN_MANIFEST_NAMES needs at least one manifest file name")
+	}
+	if !strings.Contains(args.ManifestPathTemplate, "{tag}") {
+		addf("PLUGIN_MANIFEST_PATH_TEMPLATE %q needs to contain {tag}", args.ManifestPathTemplate)
+	}
+	if args.TagPattern != "" {
+		if _, err := path.Match(args.TagPattern, ""); err != nil {
+			addf("PLUGIN_TAG_PATTERN %q is not a valid glob pattern", args.TagPattern)
+		}
+		if args.SearchMode == "digest" {
+			addf("PLUGIN_TAG_PATTERN cannot be used with PLUGIN_SEARCH_MODE digest, the matched tags are not pinned to digests")
+		}
+	}
+	if settings, err := parseImageSettings(args.ImageSettings); err != nil {
+		addf("PLUGIN_IMAGE_SETTINGS: %v", err)
+	} else {
+		refs := strings.Split(args.DockerImage, ",")
+		for i := range refs {
+			refs[i] = strings.TrimSpace(refs[i])
+		}
+		var imageRefs []string
+		for ref := range settings {
+			imageRefs = append(imageRefs, ref)
+		}
+		sort.Strings(imageRefs)
+		moduleIDs := map[string]bool{}
+		for _, ref := range imageRefs {
+			setting := settings[ref]
+			if !slices.Contains(refs, ref) {
+				addf("PLUGIN_IMAGE_SETTINGS has settings for %q, which is not one of the images", ref)
+			}
+			if setting.ModuleID == "" {
+				continue
+			}
+			if args.TagPattern != "" {
+				addf("PLUGIN_IMAGE_SETTINGS cannot set the module ID of %q with PLUGIN_TAG_PATTERN, every matching tag would get the same ID", ref)
+			}
+			if moduleIDs[setting.ModuleID] {
+				addf("PLUGIN_IMAGE_SETTINGS sets module ID %q for more than one image", setting.ModuleID)
+			}
+			moduleIDs[setting.ModuleID] = true
+		}
+	}
+	for _, patterns := range []struct{ env, value string }{
+		{"PLUGIN_MODULE_ARTIFACTS_INCLUDE", args.ArtifactsInclude},
+		{"PLUGIN_MODULE_ARTIFACTS_EXCLUDE", args.ArtifactsExclude},
+	} {
+		for _, pattern := range splitPatterns(patterns.value) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				addf("%s pattern %q is not a valid glob pattern", patterns.env, pattern)
+			}
+		}
+	}
+	if args.BuildInfoPatch != "" {
+		if _, err := parseBuildInfoPatch(args.BuildInfoPatch); err != nil {
+			addf("PLUGIN_BUILDINFO_PATCH: %v", err)
+		}
+	}
+	if _, err := parseRegistryMap(args.RegistryMap); err != nil {
+		addf("PLUGIN_REGISTRY_MAP: %v", err)
+	}
+	if args.OCIArtifact && args.LayerDependencies {
+		addf("PLUGIN_LAYER_DEPENDENCIES cannot be used with PLUGIN_OCI_ARTIFACT, the layers of OCI artifacts are recorded as artifacts")
+	}
+	for _, count := range []struct{ env, value string }{
+		{"PLUGIN_TESTS_PASSED", args.TestsPassed},
+		{"PLUGIN_TESTS_FAILED", args.TestsFailed},
+		{"PLUGIN_TESTS_SKIPPED", args.TestsSkipped},
+	} {
+		if n, err := strconv.Atoi(count.value); count.value != "" && (err != nil || n < 0) {
+			addf("%s %q is not a test count", count.env, count.value)
+		}
+	}
+	if args.Concurrency < 1 {
+		addf("PLUGIN_CONCURRENCY %d needs to be at least 1", args.Concurrency)
+	}
+	checkOneOf("PLUGIN_LABEL_PROPERTIES", args.LabelProperties, "build", "artifact", "both")
+	checkOneOf("PLUGIN_ARTIFACT_BUILD_PROPERTIES", args.ArtifactBuildProperties, "manifest", "all")
+	checkOneOf("PLUGIN_TARGET_ACTION", args.TargetAction, "copy", "move")
+	checkOneOf("PLUGIN_VERIFY", args.Verify, "warn", "fail")
+	checkOneOf("PLUGIN_PRINCIPAL_FAILURE", args.PrincipalFailure, "warn", "error")
+	checkOneOf("PLUGIN_CLEANUP_ON_FAILURE", args.CleanupOnFailure, "partials", "build")
+	checkOneOf("PLUGIN_SEARCH_MODE", args.SearchMode, "path", "digest")
+	checkOneOf("PLUGIN_SEARCH_API", args.SearchAPI, "cli", "rest")
+
+	if args.HelmChartName != "" && (args.HelmChartVersion == "" || args.HelmRepo == "") {
+		addf("PLUGIN_HELM_CHART_VERSION and PLUGIN_HELM_REPO are required with PLUGIN_HELM_CHART_NAME")
+	}
+	if args.IssuesKeyGroupIndex < 0 {
+		addf("PLUGIN_ISSUES_KEY_GROUP_INDEX needs to be zero or more")
+	}
+	if args.IssuesSummaryGroupIndex < 0 {
+		addf("PLUGIN_ISSUES_SUMMARY_GROUP_INDEX needs to be zero or more")
+	}
+	if args.IssuesRegexp != "" && args.IssuesConfig == "" && args.IssuesTrackerName == "" {
+		addf("PLUGIN_ISSUES_TRACKER_NAME is required with PLUGIN_ISSUES_REGEXP")
+	}
+
+	if args.BuildStarted != "" {
+		if _, err := parseBuildStarted(args.BuildStarted); err != nil {
+			addf("PLUGIN_BUILD_STARTED: %w", err)
+		}
+	}
+	if args.VCSEntries != "" {
+		if _, err := parseVCSEntries(args.VCSEntries); err != nil {
+			addf("PLUGIN_VCS_ENTRIES: %w", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// vcsEntry is an entry of the build info "vcs" section.
+type vcsEntry struct {
+	URL      string `json:"url"`
+	Revision string `json:"revision"`
+	Branch   string `json:"branch"`
+	Message  string `json:"message"`
+}
+
+// parseVCSEntries parses a JSON list of VCS entries.
+func parseVCSEntries(value string) ([]vcsEntry, error) {
+	var entries []vcsEntry
+	if err := json.Unmarshal([]byte(value), &entries); err != nil {
+		return nil, fmt.Errorf("error parsing vcs entries: %w", err)
+	}
+	for i, entry := range entries {
+		if entry.URL == "" || entry.Revision == "" {
+			return nil, fmt.Errorf("vcs entry %d must have a url and a revision", i+1)
+		}
+	}
+	return entries, nil
+}
+
+// addVCSEntries returns an edit that appends entries to the build info "vcs" section.
+func addVCSEntries(entries []vcsEntry) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		existing, _ := buildInfo["vcs"].([]interface{})
+		for _, entry := range entries {
+			vcs := map[string]interface{}{"url": entry.URL, "revision": entry.Revision}
+			if entry.Branch != "" {
+				vcs["branch"] = entry.Branch
+			}
+			if entry.Message != "" {
+				vcs["message"] = entry.Message
+			}
+			existing = append(existing, vcs)
+		}
+		buildInfo["vcs"] = existing
+		return nil
+	}
+}
+
+// overrideVCS returns an edit that sets the non-empty fields of entry on the
+// first entry of the build info "vcs" section, adding the entry if there is none.
+func overrideVCS(entry vcsEntry) buildInfoEdit {
+	return func(buildInfo map[string]interface{}) error {
+		existing, _ := buildInfo["vcs"].([]interface{})
+		var vcs map[string]interface{}
+		if len(existing) > 0 {
+			vcs, _ = existing[0].(map[string]interface{})
+		}
+		if vcs == nil {
+			vcs = map[string]interface{}{}
+			existing = append([]interface{}{vcs}, existing...)
+		}
+		for field, value := range map[string]string{
+			"url":      entry.URL,
+			"revision": entry.Revision,
+			"branch":   entry.Branch,
+			"message":  entry.Message,
+		} {
+			if value != "" {
+				vcs[field] = value
+			}
+		}
+		buildInfo["vcs"] = existing
+		return nil
+	}
+}
+
+// applyCIFallbacks fills in the VCS details and workspace from the variables
+// GitHub Actions or GitLab CI set, for any DRONE_* variables that are empty.
+// This lets the plugin run as a generic container step outside of Drone.
+func applyCIFallbacks(args *Args) {
+	var repoURL, commit, branch, message, workspace string
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		if server, repo := os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"); server != "" && repo != "" {
+			repoURL = strings.TrimSuffix(server, "/") + "/" + repo + ".git"
+		}
+		commit = os.Getenv("GITHUB_SHA")
+		// GITHUB_HEAD_REF is only set for pull requests, where GITHUB_REF_NAME is the merge ref
+		branch = firstNonEmpty(os.Getenv("GITHUB_HEAD_REF"), os.Getenv("GITHUB_REF_NAME"))
+		workspace = os.Getenv("GITHUB_WORKSPACE")
+	case os.Getenv("GITLAB_CI") == "true":
+		// CI_REPOSITORY_URL embeds a job token, so the project URL is used instead
+		if projectURL := os.Getenv("CI_PROJECT_URL"); projectURL != "" {
+			repoURL = projectURL + ".git"
+		}
+		commit = os.Getenv("CI_COMMIT_SHA")
+		branch = firstNonEmpty(os.Getenv("CI_COMMIT_BRANCH"), os.Getenv("CI_MERGE_REQUEST_SOURCE_BRANCH_NAME"), os.Getenv("CI_COMMIT_REF_NAME"))
+		message = os.Getenv("CI_COMMIT_MESSAGE")
+		workspace = os.Getenv("CI_PROJECT_DIR")
+	default:
+		return
+	}
+
+	args.RepoURL = firstNonEmpty(args.RepoURL, repoURL)
+	args.CommitSha = firstNonEmpty(args.CommitSha, commit)
+	args.BranchName = firstNonEmpty(args.BranchName, branch)
+	args.CommitMessage = firstNonEmpty(args.CommitMessage, message)
+	args.DefaultPath = firstNonEmpty(args.DefaultPath, workspace)
+}
+
+// commitProperties returns build-info properties describing the built commit,
+// so release notes and audits can be produced from Artifactory alone. Values
+// that are not set are left out.
+func commitProperties(args Args) map[string]string {
+	properties := map[string]string{}
+	for property, value := range map[string]string{
+		"vcs.commit.message":      args.CommitMessage,
+		"vcs.commit.author.name":  firstNonEmpty(args.CommitAuthorName, args.CommitAuthor),
+		"vcs.commit.author.email": args.CommitAuthorEmail,
+		"vcs.commit.link":         args.CommitLink,
+	} {
+		if value != "" {
+			properties[property] = value
+		}
+	}
+	return properties
+}
+
+// pullRequestProperties returns build-info properties describing the pull
+// request a build was triggered by, or nil when it was not, so a published
+// image can be traced back to the code review.
+func pullRequestProperties(args Args) map[string]string {
+	number := os.Getenv("DRONE_PULL_REQUEST")
+	if number == "" {
+		return nil
+	}
+	properties := map[string]string{"vcs.pr.number": number}
+	for property, value := range map[string]string{
+		"vcs.pr.title":         os.Getenv("DRONE_PULL_REQUEST_TITLE"),
+		"vcs.pr.source.branch": os.Getenv("DRONE_SOURCE_BRANCH"),
+		"vcs.pr.target.branch": os.Getenv("DRONE_TARGET_BRANCH"),
+		// For pull request events the commit link points to the pull request
+		"vcs.pr.link": args.CommitLink,
+	} {
+		if value != "" {
+			properties[property] = value
+		}
+	}
+	return properties
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// expectedBuildInfo is what the published build info is checked against.
+type expectedBuildInfo struct {
+	Modules   []string
+	Principal string
+	VCS       vcsEntry
+}
+
+// verifyBuildInfo fetches the published build back and checks that the docker
+// module, the VCS details and the principal made it into the stored build
+// info. Problems are logged as a warning, or returned as an error when
+// PLUGIN_VERIFY is "fail". PLUGIN_PRINCIPAL_FAILURE sets the severity of a
+// missing principal separately, and with PLUGIN_VERIFY unset only the
+// principal is checked.
+func verifyBuildInfo(ctx context.Context, args Args, sanitizedURL string, expected expectedBuildInfo) error {
+	buildInfo, err := fetchBuildInfo(ctx, args, sanitizedURL)
+	if err != nil {
+		return err
+	}
+
+	var errs, warnings []string
+	report := func(problems []string, severity string) {
+		switch severity {
+		case "fail", "error":
+			errs = append(errs, problems...)
+		case "warn":
+			warnings = append(warnings, problems...)
+		}
+	}
+	report(compareBuildInfo(buildInfo, expected), args.Verify)
+	if problem := comparePrincipal(buildInfo, expected.Principal); problem != "" {
+		report([]string{problem}, firstNonEmpty(args.PrincipalFailure, args.Verify))
+	}
+
+	if len(warnings) > 0 {
+		logrus.Warnf("Published build info does not match:\n%s", strings.Join(warnings, "\n"))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("published build info does not match:\n%s", strings.Join(errs, "\n"))
+	}
+	if len(warnings) == 0 {
+		logrus.Info("Published build info verified")
+	}
+	return nil
+}
+
+// fetchBuildInfo returns the build info of the build as stored in Artifactory.
+func fetchBuildInfo(ctx context.Context, args Args, sanitizedURL string) (map[string]interface{}, error) {
+	requestURL := buildAPIURL(sanitizedURL, args, nil, args.BuildName, args.BuildNumber)
+	req, err := newRequest(ctx, http.MethodGet, requestURL, nil, args)
+	if err != nil {
+		return nil, err
+	}
+	body, err := doRequest(req, args)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching published build info: %w", err)
+	}
+
+	var response struct {
+		BuildInfo map[string]interface{} `json:"buildInfo"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("error parsing published build info: %w", err)
+	}
+	if response.BuildInfo == nil {
+		return nil, errors.New("published build info is empty")
+	}
+	return response.BuildInfo, nil
+}
+
+// compareBuildInfo returns a line for each expected module or VCS section
+// missing from the build info.
+func compareBuildInfo(buildInfo map[string]interface{}, expected expectedBuildInfo) []string {
+	var problems []string
+
+	for _, moduleID := range expected.Modules {
+		if module := dockerModule(buildInfo, moduleID); module == nil {
+			problems = append(problems, fmt.Sprintf("modules: expected docker module %s, got none", moduleID))
+		} else if artifacts, _ := module["artifacts"].([]interface{}); len(artifacts) == 0 {
+			problems = append(problems, fmt.Sprintf("modules: expected artifacts in docker module %s, got none", moduleID))
+		}
+	}
+
+	// The URL build-add-git reads from the repository may differ from the
+	// pipeline's, so it is only compared when it was set explicitly
+	if expected.VCS.Revision != "" {
+		vcsList, _ := buildInfo["vcs"].([]interface{})
+		found := false
+		for _, v := range vcsList {
+			vcs, _ := v.(map[string]interface{})
+			if vcs["revision"] == expected.VCS.Revision && (expected.VCS.URL == "" || vcs["url"] == expected.VCS.URL) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			want := fmt.Sprintf("revision %q", expected.VCS.Revision)
+			if expected.VCS.URL != "" {
+				want = fmt.Sprintf("url %q %s", expected.VCS.URL, want)
+			}
+			problems = append(problems, fmt.Sprintf("vcs: expected an entry with %s, got %d entries without it", want, len(vcsList)))
+		}
+	}
+
+	return problems
+}
+
+// comparePrincipal returns a line describing a principal that did not apply.
+func comparePrincipal(buildInfo map[string]interface{}, expected string) string {
+	if expected == "" {
+		return ""
+	}
+	if principal, _ := buildInfo["principal"].(string); principal != expected {
+		return fmt.Sprintf("principal: expected %q, got %q", expected, principal)
+	}
+	return ""
+}
@@ -0,0 +1,15 @@
+package main
+
+import "fmt"
+
+// Version information, set at build time with
+// -ldflags "-X main.version=<version> -X main.commit=<sha>".
+var (
+	version = "dev"
+	commit  = "unknown"
+)
+
+// versionString returns the plugin name, version and commit.
+func versionString() string {
+	return fmt.Sprintf("%s %s (commit %s)", serviceName, version, commit)
+}